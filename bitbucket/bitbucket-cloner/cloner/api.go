@@ -0,0 +1,114 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// errNotFound is returned by get for 404 responses, so callers can probe endpoints missing on older servers.
+var errNotFound = errors.New("not found")
+
+// credentials authenticate both the REST API calls and the git operations. Either an app password, which needs
+// the username it belongs to, or an HTTP access token is set.
+type credentials struct {
+	username    string
+	appPassword string
+	token       string
+}
+
+func (c credentials) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return
+	}
+	req.SetBasicAuth(c.username, c.appPassword)
+}
+
+// gitAuth returns the git credentials. Access tokens without a username use x-token-auth, the username
+// Bitbucket expects for project and repository tokens.
+func (c credentials) gitAuth() transport.AuthMethod {
+	if c.token == "" {
+		return &githttp.BasicAuth{Username: c.username, Password: c.appPassword}
+	}
+	username := c.username
+	if username == "" {
+		username = "x-token-auth"
+	}
+	return &githttp.BasicAuth{Username: username, Password: c.token}
+}
+
+// apiClient is a minimal JSON client shared by the Bitbucket Cloud and Server providers.
+type apiClient struct {
+	creds credentials
+	http  *http.Client
+}
+
+func newAPIClient(creds credentials) *apiClient {
+	return &apiClient{creds: creds, http: &http.Client{Timeout: time.Minute}}
+}
+
+// get fetches url and decodes its JSON body into v, which is left untouched by 204 No Content responses.
+func (c *apiClient) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	c.creds.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("GET %s: %w", url, errNotFound)
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s: %s", url, resp.Status, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	return nil
+}
+
+// cloneLink is an entry of the clone links of a repository, named https or ssh on Cloud, http or ssh on Server.
+type cloneLink struct {
+	Name string `json:"name"`
+	Href string `json:"href"`
+}
+
+// httpCloneURL picks the HTTP clone link, without the username Bitbucket embeds in it, so the configured
+// credentials are used instead.
+func httpCloneURL(links []cloneLink) string {
+	for _, link := range links {
+		if link.Name == "https" || link.Name == "http" {
+			return stripUserInfo(link.Href)
+		}
+	}
+	return ""
+}
+
+func stripUserInfo(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
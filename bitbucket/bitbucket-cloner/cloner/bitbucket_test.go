@@ -0,0 +1,285 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+)
+
+// fakeServer serves the repositories of the ACME project of a Bitbucket Server, perPage at a time, and records
+// the requested start offsets. Default branches are served by the Bitbucket 8 endpoint, except for legacy,
+// whose instance only knows branches/default, empty, which has none, and broken, which fails.
+type fakeServer struct {
+	slugs   []string
+	perPage int
+
+	mu     sync.Mutex
+	starts []string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, `{"errors":[{"message":"Authentication failed"}]}`, http.StatusUnauthorized)
+		return
+	}
+
+	const prefix = "/rest/api/1.0/projects/ACME/repos"
+	if r.URL.Path == prefix {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		f.mu.Lock()
+		f.starts = append(f.starts, r.URL.Query().Get("start"))
+		f.mu.Unlock()
+
+		end := min(start+f.perPage, len(f.slugs))
+		page := serverPage{IsLastPage: end == len(f.slugs), NextPageStart: end}
+		for _, slug := range f.slugs[start:end] {
+			repo := serverRepository{Slug: slug}
+			repo.Project.Key = "ACME"
+			repo.Links.Clone = []cloneLink{
+				{Name: "ssh", Href: "ssh://git@bitbucket.example.com:7999/acme/" + slug + ".git"},
+				{Name: "http", Href: "https://jdoe@bitbucket.example.com/scm/acme/" + slug + ".git"},
+			}
+			page.Values = append(page.Values, repo)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+		return
+	}
+
+	slug, endpoint, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix+"/"), "/")
+	switch {
+	case slug == "broken":
+		http.Error(w, `{"errors":[{"message":"Internal server error"}]}`, http.StatusInternalServerError)
+	case slug == "empty" && endpoint == "default-branch":
+		w.WriteHeader(http.StatusNoContent)
+	case slug == "legacy" && endpoint == "branches/default":
+		_ = json.NewEncoder(w).Encode(serverBranch{DisplayID: "develop"})
+	case slug != "legacy" && slug != "empty" && endpoint == "default-branch":
+		_ = json.NewEncoder(w).Encode(serverBranch{DisplayID: "main"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeServer(t *testing.T, slugs ...string) (*fakeServer, *serverProvider) {
+	t.Helper()
+
+	fake := &fakeServer{slugs: slugs, perPage: 2}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, &serverProvider{client: newAPIClient(credentials{token: "secret"}), baseURL: server.URL, project: "ACME"}
+}
+
+// fakeCloud serves the repositories of the acme workspace of Bitbucket Cloud, perPage at a time with next links,
+// and records the requested pages.
+type fakeCloud struct {
+	url     string
+	repos   []cloudRepository
+	perPage int
+
+	mu    sync.Mutex
+	pages []string
+}
+
+func (f *fakeCloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != "jdoe" || password != "secret" {
+		http.Error(w, `{"error":{"message":"Unauthorized"}}`, http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/repositories/acme" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	f.mu.Lock()
+	f.pages = append(f.pages, strconv.Itoa(page))
+	f.mu.Unlock()
+
+	start := min((page-1)*f.perPage, len(f.repos))
+	end := min(start+f.perPage, len(f.repos))
+	body := cloudPage{Values: f.repos[start:end]}
+	if end < len(f.repos) {
+		body.Next = fmt.Sprintf("%s/repositories/acme?pagelen=%d&page=%d", f.url, f.perPage, page+1)
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newFakeCloud(t *testing.T) (*fakeCloud, *cloudProvider) {
+	t.Helper()
+
+	fake := &fakeCloud{perPage: 2}
+	for _, slug := range []string{"api", "web", "infra", "empty"} {
+		var repo cloudRepository
+		if err := json.Unmarshal([]byte(`{
+			"slug": "`+slug+`",
+			"full_name": "acme/`+slug+`",
+			"mainbranch": {"name": "main"},
+			"project": {"key": "PRJ"},
+			"links": {"clone": [{"name": "https", "href": "https://jdoe@bitbucket.org/acme/`+slug+`.git"}]}
+		}`), &repo); err != nil {
+			t.Fatal(err)
+		}
+		if slug == "empty" {
+			repo.Mainbranch = nil
+		}
+		fake.repos = append(fake.repos, repo)
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+	return fake, &cloudProvider{client: newAPIClient(credentials{username: "jdoe", appPassword: "secret"}), api: server.URL, workspace: "acme"}
+}
+
+// listRepositories returns the repositories listed by p.
+func listRepositories(t *testing.T, p reposync.Provider) []reposync.Repository {
+	t.Helper()
+
+	var repos []reposync.Repository
+	err := p.List(context.Background(), func(repo reposync.Repository) error {
+		repos = append(repos, repo)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return repos
+}
+
+// describe summarises repos as full name, default branch and emptiness.
+func describe(repos []reposync.Repository) string {
+	var parts []string
+	for _, repo := range repos {
+		part := repo.FullName + "@" + repo.DefaultBranch
+		if repo.Empty {
+			part += "(empty)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestServerListPaginates(t *testing.T) {
+	fake, p := newFakeServer(t, "api", "web", "legacy", "empty", "docs")
+
+	repos := listRepositories(t, p)
+	// legacy falls back to branches/default, and empty has no default branch at all.
+	if got, want := describe(repos), "ACME/api@main,ACME/web@main,ACME/legacy@develop,ACME/empty@(empty),ACME/docs@main"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+	if got := strings.Join(fake.starts, ","); got != "0,2,4" {
+		t.Errorf("requested starts %s, want 0,2,4", got)
+	}
+	if got, want := repos[0].CloneURL, "https://bitbucket.example.com/scm/acme/api.git"; got != want {
+		t.Errorf("clone URL %s, want the HTTP link without the username, %s", got, want)
+	}
+}
+
+func TestServerDefaultBranchErrors(t *testing.T) {
+	_, p := newFakeServer(t, "api", "broken", "web")
+
+	var listed []string
+	err := p.List(context.Background(), func(repo reposync.Repository) error {
+		listed = append(listed, repo.FullName)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "ACME/broken") || !strings.Contains(err.Error(), "500") {
+		t.Errorf("List() error = %v, want the 500 of the ACME/broken default branch", err)
+	}
+	if got := strings.Join(listed, ","); got != "ACME/api" {
+		t.Errorf("listed %s before the error, want ACME/api", got)
+	}
+}
+
+func TestServerListRequiresCredentials(t *testing.T) {
+	_, p := newFakeServer(t, "api")
+	p.client.creds.token = "wrong"
+
+	err := p.List(context.Background(), func(reposync.Repository) error {
+		t.Error("no repository should be listed without valid credentials")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("List() error = %v, want a 401 error", err)
+	}
+}
+
+func TestCloudListPaginates(t *testing.T) {
+	fake, p := newFakeCloud(t)
+
+	repos := listRepositories(t, p)
+	if got, want := describe(repos), "acme/api@main,acme/web@main,acme/infra@main,acme/empty@(empty)"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+	if got := strings.Join(fake.pages, ","); got != "1,2" {
+		t.Errorf("requested pages %s, want 1,2", got)
+	}
+	if got, want := repos[0].Path, "PRJ/api"; got != want {
+		t.Errorf("path %s, want %s under the project key", got, want)
+	}
+}
+
+func TestCloudListRequiresCredentials(t *testing.T) {
+	_, p := newFakeCloud(t)
+	p.client.creds.appPassword = "wrong"
+
+	err := p.List(context.Background(), func(reposync.Repository) error {
+		t.Error("no repository should be listed without valid credentials")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("List() error = %v, want a 401 error", err)
+	}
+}
+
+func TestEmptyRepositoriesAreInitialised(t *testing.T) {
+	_, p := newFakeCloud(t)
+	base := t.TempDir()
+	syncer := reposync.New(p, reposync.Options{
+		BaseDir:        base,
+		FallbackBranch: defaultBranch,
+		Prepare: func(ctx context.Context, task *reposync.Task) error {
+			// Leave the others out, so nothing is cloned from the fake workspace.
+			if !task.Empty {
+				return &reposync.Skip{Status: reposync.StatusUnchanged}
+			}
+			return nil
+		},
+	})
+
+	results, err := syncer.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Errorf("%s: %v", res.Task.FullName, res.Err)
+		}
+	}
+	if got := reposync.Count(results, reposync.StatusUpdated); got != 1 {
+		t.Fatalf("%d repositories updated, want the empty one", got)
+	}
+
+	r, err := git.PlainOpen(filepath.Join(base, "PRJ", "empty"))
+	if err != nil {
+		t.Fatalf("empty repository not initialised: %v", err)
+	}
+	origin, err := r.Remote("origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := origin.Config().URLs[0], "https://bitbucket.org/acme/empty.git"; got != want {
+		t.Errorf("origin %s, want %s", got, want)
+	}
+}
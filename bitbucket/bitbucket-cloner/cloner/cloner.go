@@ -0,0 +1,89 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/pterm/pterm"
+)
+
+var defaultBranch = "master"
+
+// flags holds every option of bitbucket-cloner. The standalone binary parses it from the command line, and
+// dxutils exposes it on its "clone bitbucket" command.
+var flags = flag.NewFlagSet("bitbucket-cloner", flag.ExitOnError)
+
+var timeoutFlag = flags.String("timeout", "2m", "timeout duration for git operations")
+var baseDirFlag = flags.String("path", "./", "Path to clone repositories")
+var urlFlag = flags.String("url", "", "Bitbucket Server or Data Center URL, defaults to BITBUCKET_URL. Leave empty for Bitbucket Cloud")
+var workspaceFlag = flags.String("workspace", "", "Bitbucket Cloud workspace to clone from")
+var projectFlag = flags.String("project", "", "project key to clone from, every visible repository if empty")
+var includeArchivedFlag = flags.Bool("include-archived", false, "clone archived repositories too")
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run clones or updates every repository of the Bitbucket Cloud workspace or Bitbucket Server project.
+func Run(ctx context.Context) error {
+	creds := credentials{
+		username:    os.Getenv("BITBUCKET_USERNAME"),
+		appPassword: os.Getenv("BITBUCKET_APP_PASSWORD"),
+		token:       os.Getenv("BITBUCKET_TOKEN"),
+	}
+	if creds.token == "" && (creds.username == "" || creds.appPassword == "") {
+		return errors.New("either BITBUCKET_TOKEN, or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD must be set")
+	}
+
+	baseURL := *urlFlag
+	if baseURL == "" {
+		baseURL = os.Getenv("BITBUCKET_URL")
+	}
+
+	var provider reposync.Provider
+	client := newAPIClient(creds)
+	if baseURL == "" {
+		if *workspaceFlag == "" {
+			return errors.New("please specify a Bitbucket Cloud workspace with the -workspace option, or a server with -url")
+		}
+		provider = &cloudProvider{client: client, api: cloudAPI, workspace: *workspaceFlag, project: *projectFlag}
+	} else {
+		provider = &serverProvider{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), project: *projectFlag}
+	}
+
+	timeout, err := time.ParseDuration(*timeoutFlag)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration: %s", *timeoutFlag)
+	}
+
+	pterm.Info.Printf("Cloning repositories from %s to %s\n", provider.Name(), *baseDirFlag)
+
+	reporter := reposync.NewProgressReporter("Cloning Bitbucket Repositories")
+	syncer := reposync.New(provider, reposync.Options{
+		BaseDir:         *baseDirFlag,
+		Timeout:         timeout,
+		FallbackBranch:  defaultBranch,
+		IncludeArchived: *includeArchivedFlag,
+		GitProgress:     os.Stdout,
+		Reporter:        reporter,
+	})
+
+	// Listing errors are already reported by the progress reporter.
+	results, _ := syncer.Run(ctx)
+	reporter.Stop()
+
+	if len(results) == 0 {
+		pterm.Warning.Println("No repositories found")
+		return nil
+	}
+
+	pterm.Success.Printf("Cloned %d repositories from %s\n", reposync.Count(results, reposync.StatusUpdated), provider.Name())
+	return nil
+}
@@ -0,0 +1,97 @@
+package cloner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const cloudAPI = "https://api.bitbucket.org/2.0"
+
+// cloudRepository is the subset of the Bitbucket Cloud repository object the cloner needs.
+type cloudRepository struct {
+	Slug       string `json:"slug"`
+	FullName   string `json:"full_name"`
+	Size       int64  `json:"size"` // In bytes
+	Mainbranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Project *struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	UpdatedOn time.Time `json:"updated_on"`
+	Links     struct {
+		Clone []cloneLink `json:"clone"`
+	} `json:"links"`
+}
+
+// cloudPage is a page of the Bitbucket Cloud API. Next is the URL of the following page, empty on the last one.
+type cloudPage struct {
+	Values []cloudRepository `json:"values"`
+	Next   string            `json:"next"`
+}
+
+// cloudProvider lists the repositories of a Bitbucket Cloud workspace, optionally restricted to one project.
+type cloudProvider struct {
+	client *apiClient
+	// api is the base URL of the Bitbucket Cloud API, cloudAPI unless testing.
+	api       string
+	workspace string
+	project   string
+}
+
+func (p *cloudProvider) Name() string {
+	return "Bitbucket Cloud"
+}
+
+func (p *cloudProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	query := url.Values{"pagelen": {"100"}}
+	if p.project != "" {
+		query.Set("q", fmt.Sprintf("project.key=%q", p.project))
+	}
+	next := fmt.Sprintf("%s/repositories/%s?%s", p.api, url.PathEscape(p.workspace), query.Encode())
+
+	for next != "" {
+		var page cloudPage
+		if err := p.client.get(ctx, next, &page); err != nil {
+			return err
+		}
+
+		for _, repo := range page.Values {
+			if err := fn(cloudRepositoryToRepository(repo)); err != nil {
+				return err
+			}
+		}
+		next = page.Next
+	}
+	return nil
+}
+
+func (p *cloudProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return p.client.creds.gitAuth()
+}
+
+func cloudRepositoryToRepository(repo cloudRepository) reposync.Repository {
+	r := reposync.Repository{
+		Path:     repo.Slug,
+		FullName: repo.FullName,
+		CloneURL: httpCloneURL(repo.Links.Clone),
+		SizeKB:   repo.Size / 1024,
+		PushedAt: repo.UpdatedOn,
+	}
+	if repo.Project != nil {
+		r.Path = path.Join(repo.Project.Key, repo.Slug)
+	}
+	// Repositories without commits have no main branch yet.
+	if repo.Mainbranch != nil {
+		r.DefaultBranch = repo.Mainbranch.Name
+	} else {
+		r.Empty = true
+	}
+	return r
+}
@@ -0,0 +1,116 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// serverRepository is the subset of the Bitbucket Server and Data Center repository object the cloner needs.
+type serverRepository struct {
+	Slug     string `json:"slug"`
+	Archived bool   `json:"archived"`
+	Project  struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links struct {
+		Clone []cloneLink `json:"clone"`
+	} `json:"links"`
+}
+
+// serverPage is a page of the Bitbucket Server API, which paginates with start offsets.
+type serverPage struct {
+	Values        []serverRepository `json:"values"`
+	IsLastPage    bool               `json:"isLastPage"`
+	NextPageStart int                `json:"nextPageStart"`
+}
+
+type serverBranch struct {
+	DisplayID string `json:"displayId"`
+}
+
+// serverProvider lists the repositories of a Bitbucket Server or Data Center project, or every repository
+// visible to the credentials when no project is given.
+type serverProvider struct {
+	client  *apiClient
+	baseURL string
+	project string
+}
+
+func (p *serverProvider) Name() string {
+	return "Bitbucket Server"
+}
+
+func (p *serverProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	endpoint := p.baseURL + "/rest/api/1.0/repos"
+	if p.project != "" {
+		endpoint = fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos", p.baseURL, url.PathEscape(p.project))
+	}
+
+	start := 0
+	for {
+		var page serverPage
+		if err := p.client.get(ctx, fmt.Sprintf("%s?limit=100&start=%d", endpoint, start), &page); err != nil {
+			return err
+		}
+
+		for _, repo := range page.Values {
+			r, err := p.repository(ctx, repo)
+			if err != nil {
+				return err
+			}
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			return nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+func (p *serverProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return p.client.creds.gitAuth()
+}
+
+// repository maps a listed repository, looking up its default branch since the listing doesn't include it.
+// Repositories without a default branch have no commits yet, and are initialised as empty clones.
+func (p *serverProvider) repository(ctx context.Context, repo serverRepository) (reposync.Repository, error) {
+	r := reposync.Repository{
+		Path:     path.Join(repo.Project.Key, repo.Slug),
+		FullName: repo.Project.Key + "/" + repo.Slug,
+		CloneURL: httpCloneURL(repo.Links.Clone),
+		Archived: repo.Archived,
+	}
+
+	branch, err := p.defaultBranch(ctx, repo)
+	if err != nil {
+		return r, fmt.Errorf("failed to get the default branch of %s: %w", r.FullName, err)
+	}
+	r.DefaultBranch = branch
+	r.Empty = branch == ""
+	return r, nil
+}
+
+// defaultBranch uses the default-branch endpoint of Bitbucket 8 and falls back to the older branches/default
+// one. An empty name is returned when neither knows a default branch, as for empty repositories.
+func (p *serverProvider) defaultBranch(ctx context.Context, repo serverRepository) (string, error) {
+	base := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s", p.baseURL, url.PathEscape(repo.Project.Key), url.PathEscape(repo.Slug))
+
+	var branch serverBranch
+	err := p.client.get(ctx, base+"/default-branch", &branch)
+	if errors.Is(err, errNotFound) || (err == nil && branch.DisplayID == "") {
+		err = p.client.get(ctx, base+"/branches/default", &branch)
+	}
+	if errors.Is(err, errNotFound) {
+		return "", nil
+	}
+	return branch.DisplayID, err
+}
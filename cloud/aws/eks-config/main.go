@@ -1,42 +1,18 @@
 package main
 
 import (
-	"github.com/Excoriate/dxutils/eksconfig/pkg/aws"
+	"context"
 	"log"
 	"os"
+
+	"github.com/Excoriate/dxutils/eksconfig/pkg/eksconfig"
 )
 
 func main() {
-	awsProfile := os.Getenv("AWS_PROFILE")
-	if awsProfile == "" {
-		log.Fatalf("AWS_PROFILE environment variable is not set")
-	}
-
-	awsCfg := aws.New(awsProfile)
-	profileFile, err := awsCfg.ResolveAWSConfigFilePath()
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-
-	log.Printf("AWS config file path: %s", profileFile)
+	// The flag set exits on parse errors by itself.
+	_ = eksconfig.Flags().Parse(os.Args[1:])
 
-	awsProfiles, err := awsCfg.FetchAWSProfilesFromConfigFile()
-	if err != nil {
+	if err := eksconfig.Run(context.Background()); err != nil {
 		log.Fatal(err.Error())
 	}
-
-	log.Printf("AWS profiles: %v", awsProfiles)
-
-	profile, err := awsCfg.LookupAWSProfileNameInCredentialsFile(awsProfiles, awsProfile)
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-
-	_, err = awsCfg.GetAWSClient(profile)
-	if err != nil {
-		log.Fatalf("Failed to get AWS client: %v", err)
-	}
-
-	eksClusters := awsCfg.ListEKSs()
-	log.Printf("EKS clusters: %v", eksClusters)
 }
@@ -30,10 +30,14 @@ const (
 
 type EKSCluster struct {
 	Name string
+	// Endpoint and CertificateAuthority are only set by DescribeEKS. The certificate is base64 encoded.
+	Endpoint             string
+	CertificateAuthority string
 }
 
 type Client interface {
 	ListEKSs() []EKSCluster
+	DescribeEKS(ctx context.Context, name string) (EKSCluster, error)
 	FetchAWSProfilesFromConfigFile() ([]Profile, error)
 	ResolveAWSConfigFilePath() (string, error)
 	GetAWSClient(awsProfileName Profile) (aws.Config, error)
@@ -176,3 +180,19 @@ func (c *ClientImpl) ListEKSs() []EKSCluster {
 
 	return eksClusters
 }
+
+// DescribeEKS looks up the API server endpoint and certificate authority of the EKS cluster called name.
+func (c *ClientImpl) DescribeEKS(ctx context.Context, name string) (EKSCluster, error) {
+	svc := eks.NewFromConfig(c.awsClient)
+
+	resp, err := svc.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+	if err != nil {
+		return EKSCluster{}, fmt.Errorf("unable to describe cluster %s: %w", name, err)
+	}
+
+	cluster := EKSCluster{Name: name, Endpoint: aws.ToString(resp.Cluster.Endpoint)}
+	if resp.Cluster.CertificateAuthority != nil {
+		cluster.CertificateAuthority = aws.ToString(resp.Cluster.CertificateAuthority.Data)
+	}
+	return cluster, nil
+}
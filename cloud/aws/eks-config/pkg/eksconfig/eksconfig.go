@@ -0,0 +1,83 @@
+package eksconfig
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Excoriate/dxutils/eksconfig/pkg/aws"
+	"github.com/Excoriate/dxutils/eksconfig/pkg/kubernetes"
+)
+
+// flags holds every option of eks-config. The standalone binary parses it from the command line, and dxutils
+// exposes it on its "eks kubeconfig" command.
+var flags = flag.NewFlagSet("eks-config", flag.ExitOnError)
+
+var profileFlag = flags.String("profile", "", "AWS profile to use, defaults to AWS_PROFILE")
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run looks up the AWS profile in the credentials file, lists the EKS clusters it has access to and writes a
+// kubeconfig reaching all of them.
+func Run(ctx context.Context) error {
+	awsProfile := *profileFlag
+	if awsProfile == "" {
+		awsProfile = os.Getenv("AWS_PROFILE")
+	}
+	if awsProfile == "" {
+		return errors.New("AWS_PROFILE environment variable is not set")
+	}
+
+	awsCfg := aws.New(awsProfile)
+	profileFile, err := awsCfg.ResolveAWSConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("AWS config file path: %s", profileFile)
+
+	awsProfiles, err := awsCfg.FetchAWSProfilesFromConfigFile()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("AWS profiles: %v", awsProfiles)
+
+	profile, err := awsCfg.LookupAWSProfileNameInCredentialsFile(awsProfiles, awsProfile)
+	if err != nil {
+		return err
+	}
+
+	_, err = awsCfg.GetAWSClient(profile)
+	if err != nil {
+		return fmt.Errorf("failed to get AWS client: %w", err)
+	}
+
+	eksClusters := awsCfg.ListEKSs()
+	log.Printf("EKS clusters: %v", eksClusters)
+
+	kubeCfg := kubernetes.New(profile.Region, profile.ProfileName)
+	for _, cluster := range eksClusters {
+		described, err := awsCfg.DescribeEKS(ctx, cluster.Name)
+		if err != nil {
+			return err
+		}
+		kubeCfg.Clusters = append(kubeCfg.Clusters, kubernetes.EKSCluster{
+			Name:                 described.Name,
+			Endpoint:             described.Endpoint,
+			CertificateAuthority: described.CertificateAuthority,
+		})
+	}
+
+	if err := kubeCfg.GenerateNewKubeConfigs(); err != nil {
+		return fmt.Errorf("failed to generate kubeconfig: %w", err)
+	}
+	log.Printf("Kubeconfig written to %s, use it with KUBECONFIG=%s", kubeCfg.KubeConfigFile(), kubeCfg.KubeConfigFile())
+	return nil
+}
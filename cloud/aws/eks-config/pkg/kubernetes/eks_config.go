@@ -4,6 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -23,6 +27,9 @@ type EKSConfig interface {
 
 type EKSCluster struct {
 	Name string
+	// Endpoint is the URL of the API server, and CertificateAuthority its base64 encoded CA certificate.
+	Endpoint             string
+	CertificateAuthority string
 }
 
 type EKSConfigImpl struct {
@@ -63,6 +70,66 @@ func (e *EKSConfigImpl) ListKubeConfigExistingSettings() ([]string, error) {
 	return kubeConfigs, nil
 }
 
+// kubeConfig is the subset of the kubeconfig format needed to reach EKS clusters.
+type kubeConfig struct {
+	APIVersion     string            `yaml:"apiVersion"`
+	Kind           string            `yaml:"kind"`
+	Clusters       []kubeConfigEntry `yaml:"clusters"`
+	Contexts       []kubeConfigEntry `yaml:"contexts"`
+	Users          []kubeConfigEntry `yaml:"users"`
+	CurrentContext string            `yaml:"current-context,omitempty"`
+	Preferences    map[string]string `yaml:"preferences"`
+}
+
+type kubeConfigEntry struct {
+	Name    string         `yaml:"name"`
+	Cluster map[string]any `yaml:"cluster,omitempty"`
+	Context map[string]any `yaml:"context,omitempty"`
+	User    map[string]any `yaml:"user,omitempty"`
+}
+
+// KubeConfigFile returns the path of the kubeconfig written by GenerateNewKubeConfigs.
+func (e *EKSConfigImpl) KubeConfigFile() string {
+	return filepath.Join(e.KubeConfigPath, KubeConfigFileName)
+}
+
+// GenerateNewKubeConfigs writes a kubeconfig with a context per cluster to KubeConfigFile, replacing the one
+// written by a previous run. Like aws eks update-kubeconfig, users get their tokens from aws eks get-token with
+// the AWS profile, so the file holds no credentials. The first cluster is the current context.
 func (e *EKSConfigImpl) GenerateNewKubeConfigs() error {
-	return nil
+	if len(e.Clusters) == 0 {
+		return errors.New("no EKS clusters to generate a kubeconfig for")
+	}
+
+	region := strings.TrimSpace(e.Region)
+	cfg := kubeConfig{APIVersion: "v1", Kind: "Config", Preferences: map[string]string{}}
+	for _, cluster := range e.Clusters {
+		name := fmt.Sprintf("%s-%s", e.AWSProfileName, cluster.Name)
+		cfg.Clusters = append(cfg.Clusters, kubeConfigEntry{Name: name, Cluster: map[string]any{
+			"server":                     cluster.Endpoint,
+			"certificate-authority-data": cluster.CertificateAuthority,
+		}})
+		cfg.Contexts = append(cfg.Contexts, kubeConfigEntry{Name: name, Context: map[string]any{
+			"cluster": name,
+			"user":    name,
+		}})
+		cfg.Users = append(cfg.Users, kubeConfigEntry{Name: name, User: map[string]any{
+			"exec": map[string]any{
+				"apiVersion": "client.authentication.k8s.io/v1beta1",
+				"command":    "aws",
+				"args":       []string{"--region", region, "eks", "get-token", "--cluster-name", cluster.Name, "--output", "json"},
+				"env":        []map[string]string{{"name": "AWS_PROFILE", "value": e.AWSProfileName}},
+			},
+		}})
+	}
+	cfg.CurrentContext = cfg.Contexts[0].Name
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+	if err := os.MkdirAll(e.KubeConfigPath, 0700); err != nil {
+		return fmt.Errorf("failed to create kube config directory: %w", err)
+	}
+	return os.WriteFile(e.KubeConfigFile(), data, 0600)
 }
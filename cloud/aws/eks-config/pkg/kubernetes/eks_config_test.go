@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestGenerateNewKubeConfigs(t *testing.T) {
+	cfg := New(" eu-west-1", "platform")
+	cfg.KubeConfigPath = filepath.Join(t.TempDir(), ".kube")
+	cfg.Clusters = []EKSCluster{
+		{Name: "prod", Endpoint: "https://prod.eks.amazonaws.com", CertificateAuthority: "cHJvZA=="},
+		{Name: "staging", Endpoint: "https://staging.eks.amazonaws.com", CertificateAuthority: "c3RhZ2luZw=="},
+	}
+
+	if err := cfg.GenerateNewKubeConfigs(); err != nil {
+		t.Fatalf("GenerateNewKubeConfigs() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.KubeConfigPath, KubeConfigFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got kubeConfig
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid kubeconfig: %v\n%s", err, data)
+	}
+	if len(got.Clusters) != 2 || len(got.Contexts) != 2 || len(got.Users) != 2 {
+		t.Fatalf("kubeconfig has %d clusters, %d contexts and %d users, want 2 each", len(got.Clusters), len(got.Contexts), len(got.Users))
+	}
+	if got.CurrentContext != "platform-prod" {
+		t.Errorf("current context = %s, want platform-prod", got.CurrentContext)
+	}
+	if server := got.Clusters[1].Cluster["server"]; server != "https://staging.eks.amazonaws.com" {
+		t.Errorf("staging server = %v", server)
+	}
+	exec, _ := got.Users[0].User["exec"].(map[any]any)
+	args, _ := exec["args"].([]any)
+	if len(args) < 2 || args[1] != "eu-west-1" || exec["command"] != "aws" {
+		t.Errorf("prod user exec = %v, want aws eks get-token in eu-west-1", exec)
+	}
+}
+
+func TestGenerateNewKubeConfigsWithoutClusters(t *testing.T) {
+	cfg := New("eu-west-1", "platform")
+	cfg.KubeConfigPath = t.TempDir()
+
+	if err := cfg.GenerateNewKubeConfigs(); err == nil {
+		t.Error("GenerateNewKubeConfigs() succeeded without clusters")
+	}
+}
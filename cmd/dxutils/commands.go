@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/4idtsn/platform-aws-accounts-envs/tfstate"
+	bitbucketcloner "github.com/Excoriate/dxutils/bitbucket/bitbucket-cloner/cloner"
+	"github.com/Excoriate/dxutils/eksconfig/pkg/eksconfig"
+	giteacloner "github.com/Excoriate/dxutils/gitea/gitea-cloner/cloner"
+	githubcloner "github.com/Excoriate/dxutils/github/github-cloner/cloner"
+	gitlabcloner "github.com/Excoriate/dxutils/gitlab/gitlab-cloner/cloner"
+	"github.com/Excoriate/dxutils/gitlab/gitlab-tf-module-inspector/inspector"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+func newRootCommand() *cobra.Command {
+	var configPath string
+	var noColor bool
+
+	root := &cobra.Command{
+		Use:           "dxutils",
+		Short:         "Set of utilities making developers lives easier",
+		Version:       version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(configPath, cmd.Flags().Changed("config"))
+			if err != nil {
+				return err
+			}
+			if err := cfg.apply(cmd); err != nil {
+				return err
+			}
+			if noColor {
+				pterm.DisableColor()
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&configPath, "config", defaultConfigPath(), "config file with default flag values and environment")
+	root.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+
+	clone := &cobra.Command{Use: "clone", Short: "Clone or update every repository of a git hosting service"}
+	clone.AddCommand(
+		toolCommand("github", "Clone the repositories of a GitHub organization or user", githubcloner.Flags(), githubcloner.Run),
+		toolCommand("gitlab", "Clone the projects of a GitLab group, user, membership, stars or list file", gitlabcloner.Flags(), gitlabcloner.Run),
+		toolCommand("gitea", "Clone the repositories of a Gitea or Forgejo organization or user", giteacloner.Flags(), giteacloner.Run),
+		toolCommand("bitbucket", "Clone the repositories of a Bitbucket Cloud workspace or Bitbucket Server project", bitbucketcloner.Flags(), bitbucketcloner.Run),
+	)
+
+	tf := &cobra.Command{Use: "tf", Short: "Terraform utilities"}
+	tf.AddCommand(toolCommand("inspect", "Find the GitLab projects using a Terraform module", inspector.Flags(), inspector.Run))
+
+	tfState := &cobra.Command{Use: "tfstate", Short: "Terraform state utilities"}
+	tfState.AddCommand(toolCommand("bootstrap", "Create the Terraform state bucket and lock table", tfstate.Flags(), tfstate.Run))
+
+	eks := &cobra.Command{Use: "eks", Short: "EKS utilities"}
+	eks.AddCommand(toolCommand("kubeconfig", "Generate a kubeconfig for the EKS clusters of an AWS profile", eksconfig.Flags(), eksconfig.Run))
+
+	root.AddCommand(clone, tf, tfState, eks)
+	return root
+}
+
+// toolCommand exposes the flag set and Run function of a tool as a subcommand. The flags keep their names, but
+// take two dashes like every other dxutils flag.
+func toolCommand(use, short string, flags *flag.FlagSet, run func(context.Context) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd.Context())
+		},
+	}
+	cmd.Flags().AddGoFlagSet(flags)
+	return cmd
+}
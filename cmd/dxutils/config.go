@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// config holds the default flag values, nested by command path, and the environment of every tool:
+//
+//	no-color: true
+//	env:
+//	  GITHUB_TOKEN: ...
+//	clone:
+//	  path: ~/code
+//	  github:
+//	    target: my-org
+//	    org: true
+//
+// Values set on a command apply to its subcommands too, deeper ones winning. Flags given on the command line
+// always take precedence.
+type config map[string]any
+
+// defaultConfigPath returns ~/.config/dxutils/config.yaml, honouring XDG_CONFIG_HOME.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "dxutils", "config.yaml")
+}
+
+// loadConfig reads the config file. A missing file is only an error when its path was given explicitly.
+func loadConfig(path string, explicit bool) (config, error) {
+	if path == "" {
+		return config{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoding into config itself would make the nested sections config values too.
+	var cfg map[string]any
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return config(cfg), nil
+}
+
+// apply exports the env section and sets the flags of cmd that were not given on the command line.
+func (c config) apply(cmd *cobra.Command) error {
+	if env, ok := c["env"].(map[string]any); ok {
+		for name, value := range env {
+			if _, set := os.LookupEnv(name); !set {
+				if err := os.Setenv(name, fmt.Sprint(value)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// The first name of the path is the root command itself.
+	path := strings.Fields(cmd.CommandPath())[1:]
+
+	values := map[string]string{}
+	level := map[string]any(c)
+	for depth := 0; ; depth++ {
+		leaf := depth == len(path)
+		for name, value := range level {
+			if _, nested := value.(map[string]any); nested || (depth == 0 && name == "env") {
+				continue
+			}
+			if cmd.Flags().Lookup(name) == nil {
+				// Shallower levels are shared with other commands, which may be the ones having the flag.
+				if leaf {
+					return fmt.Errorf("unknown flag %q in the config of %s", name, cmd.CommandPath())
+				}
+				continue
+			}
+			values[name] = configValue(value)
+		}
+
+		if leaf {
+			break
+		}
+		next, ok := level[path[depth]].(map[string]any)
+		if !ok {
+			break
+		}
+		level = next
+	}
+
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		value, ok := values[f.Name]
+		if !ok || f.Changed || err != nil {
+			return
+		}
+		if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid config value for %s: %w", f.Name, setErr)
+		}
+	})
+	return err
+}
+
+// configValue formats a YAML value as a flag value, joining lists with commas and expanding a leading ~/ to
+// the home directory.
+func configValue(value any) string {
+	list, ok := value.([]any)
+	if !ok {
+		s := fmt.Sprint(value)
+		if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(s, "~/") {
+			s = filepath.Join(home, s[2:])
+		}
+		return s
+	}
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		items = append(items, fmt.Sprint(item))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,29 @@
+// Command dxutils bundles every tool of this repository in a single binary, one subcommand each.
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	githubcloner "github.com/Excoriate/dxutils/github/github-cloner/cloner"
+	"github.com/pterm/pterm"
+)
+
+// version is set at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		pterm.Error.Println(err)
+		// Signature policy violations are reported through a dedicated exit code, like github-cloner does.
+		if errors.Is(err, githubcloner.ErrUnverified) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,90 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/pterm/pterm"
+)
+
+var defaultBranch = "main"
+
+// flags holds every option of gitea-cloner. The standalone binary parses it from the command line, and dxutils
+// exposes it on its "clone gitea" command.
+var flags = flag.NewFlagSet("gitea-cloner", flag.ExitOnError)
+
+var timeoutFlag = flags.String("timeout", "2m", "timeout duration for git operations")
+var baseDirFlag = flags.String("path", "./", "Path to clone repositories")
+var urlFlag = flags.String("url", "", "Gitea or Forgejo instance URL, defaults to GITEA_URL")
+var targetFlag = flags.String("target", "", "Gitea organization or user to clone from")
+var isOrgFlag = flags.Bool("org", false, "Specify if the target is an organization")
+var includeArchivedFlag = flags.Bool("include-archived", false, "clone archived repositories too")
+var includeMirrorsFlag = flags.Bool("include-mirrors", false, "clone pull mirrors of other repositories too")
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run clones or updates every repository of the target organization or user.
+func Run(ctx context.Context) error {
+	token := os.Getenv("GITEA_TOKEN")
+	if token == "" {
+		return errors.New("GITEA_TOKEN not set")
+	}
+
+	baseURL := *urlFlag
+	if baseURL == "" {
+		baseURL = os.Getenv("GITEA_URL")
+	}
+	if baseURL == "" {
+		return errors.New("please specify the Gitea instance with the -url option or GITEA_URL")
+	}
+
+	if *targetFlag == "" {
+		return errors.New("please specify a target organization or user with the -target option")
+	}
+
+	timeout, err := time.ParseDuration(*timeoutFlag)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration: %s", *timeoutFlag)
+	}
+
+	pterm.Info.Printf("Cloning repositories from %s to %s\n", *targetFlag, *baseDirFlag)
+
+	provider := &giteaProvider{
+		client:         newGiteaClient(strings.TrimSuffix(baseURL, "/"), token),
+		target:         *targetFlag,
+		isOrg:          *isOrgFlag,
+		includeMirrors: *includeMirrorsFlag,
+	}
+	reporter := reposync.NewProgressReporter("Cloning Gitea Repositories")
+	syncer := reposync.New(provider, reposync.Options{
+		BaseDir:         *baseDirFlag,
+		Timeout:         timeout,
+		FallbackBranch:  defaultBranch,
+		IncludeArchived: *includeArchivedFlag,
+		GitProgress:     os.Stdout,
+		Reporter:        reporter,
+		Prepare:         skipEmpty,
+	})
+
+	// Listing errors are already reported by the progress reporter.
+	results, _ := syncer.Run(ctx)
+	reporter.Stop()
+
+	if len(results) == 0 {
+		pterm.Warning.Println("No repositories found")
+		return nil
+	}
+
+	pterm.Success.Printf("Cloned %d repositories from Gitea, %d skipped\n",
+		reposync.Count(results, reposync.StatusUpdated), reposync.Count(results, reposync.StatusSkipped))
+	return nil
+}
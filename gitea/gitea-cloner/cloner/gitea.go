@@ -0,0 +1,152 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// pageSize is the number of repositories requested per page. Instances cap it with their MAX_RESPONSE_ITEMS
+// setting, 50 by default, so pagination never relies on it being honoured.
+const pageSize = 50
+
+// giteaRepository is the subset of the Gitea repository API object the cloner needs. Forgejo serves the same
+// API.
+type giteaRepository struct {
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	CloneURL      string    `json:"clone_url"`
+	DefaultBranch string    `json:"default_branch"`
+	Archived      bool      `json:"archived"`
+	Mirror        bool      `json:"mirror"`
+	Empty         bool      `json:"empty"`
+	Size          int64     `json:"size"` // In kilobytes
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// giteaClient is a minimal client of the Gitea REST API.
+type giteaClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newGiteaClient(baseURL, token string) *giteaClient {
+	return &giteaClient{baseURL: baseURL, token: token, http: http.DefaultClient}
+}
+
+// listRepos fetches a single page of the repositories of an organization or a user, along with the total
+// number of repositories reported by the X-Total-Count header, -1 if missing.
+func (c *giteaClient) listRepos(ctx context.Context, owner string, isOrg bool, page int) ([]giteaRepository, int, error) {
+	kind := "users"
+	if isOrg {
+		kind = "orgs"
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/%s/%s/repos?page=%d&limit=%d", c.baseURL, kind, url.PathEscape(owner), page, pageSize)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("GET %s: %s: %s", endpoint, resp.Status, body)
+	}
+
+	var repos []giteaRepository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, 0, fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		total = -1
+	}
+	return repos, total, nil
+}
+
+// giteaProvider lists the repositories of a Gitea or Forgejo organization or user.
+type giteaProvider struct {
+	client         *giteaClient
+	target         string
+	isOrg          bool
+	includeMirrors bool
+}
+
+func (p *giteaProvider) Name() string {
+	return "Gitea"
+}
+
+func (p *giteaProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	listed := 0
+	for page := 1; ; page++ {
+		repos, total, err := p.client.listRepos(ctx, p.target, p.isOrg, page)
+		if err != nil {
+			return err
+		}
+
+		listed += len(repos)
+		for _, repo := range repos {
+			// Mirrors are synced from their source instead.
+			if repo.Mirror && !p.includeMirrors {
+				continue
+			}
+			if err := fn(giteaRepositoryToRepository(repo)); err != nil {
+				return err
+			}
+		}
+
+		// The page size may be capped by the instance, so stop on the reported total or an empty page rather
+		// than on a short one.
+		if len(repos) == 0 || (total >= 0 && listed >= total) {
+			return nil
+		}
+	}
+}
+
+// Auth sends the token as the username, which Gitea accepts in place of a password for HTTP git operations.
+func (p *giteaProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return &githttp.BasicAuth{
+		Username: p.client.token,
+		Password: "x-oauth-basic",
+	}
+}
+
+func giteaRepositoryToRepository(repo giteaRepository) reposync.Repository {
+	return reposync.Repository{
+		Path:          repo.Name,
+		FullName:      repo.FullName,
+		CloneURL:      repo.CloneURL,
+		DefaultBranch: repo.DefaultBranch,
+		Archived:      repo.Archived,
+		Empty:         repo.Empty,
+		SizeKB:        repo.Size,
+		PushedAt:      repo.UpdatedAt,
+	}
+}
+
+// skipEmpty leaves out empty repositories, which have nothing to check out, reporting them as skipped.
+func skipEmpty(ctx context.Context, task *reposync.Task) error {
+	if task.Empty {
+		return &reposync.Skip{Status: reposync.StatusSkipped, Reason: "empty repository"}
+	}
+	return nil
+}
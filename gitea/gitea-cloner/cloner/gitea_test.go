@@ -0,0 +1,159 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// fakeGitea serves repos from the repository listing endpoints of owner, perPage at a time as an instance with a
+// low MAX_RESPONSE_ITEMS would, and records the requested pages.
+type fakeGitea struct {
+	owner   string
+	repos   []giteaRepository
+	perPage int
+	total   bool
+
+	mu    sync.Mutex
+	pages []string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token secret" {
+		http.Error(w, `{"message":"token is required"}`, http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/api/v1/"+f.owner+"/repos" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	f.mu.Lock()
+	f.pages = append(f.pages, r.URL.Query().Get("page"))
+	f.mu.Unlock()
+
+	start := min((page-1)*f.perPage, len(f.repos))
+	end := min(start+f.perPage, len(f.repos))
+	if f.total {
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(f.repos)))
+	}
+	_ = json.NewEncoder(w).Encode(f.repos[start:end])
+}
+
+func newFakeGitea(t *testing.T, owner string, total bool) (*fakeGitea, *giteaClient) {
+	t.Helper()
+
+	fake := &fakeGitea{owner: owner, perPage: 2, total: total}
+	for _, name := range []string{"api", "web", "infra", "docs", "empty"} {
+		fake.repos = append(fake.repos, giteaRepository{
+			Name:     name,
+			FullName: "acme/" + name,
+			CloneURL: "https://gitea.example.com/acme/" + name + ".git",
+			Empty:    name == "empty",
+		})
+	}
+	fake.repos[3].Mirror = true
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, newGiteaClient(server.URL, "secret")
+}
+
+// listNames returns the full names listed by p.
+func listNames(t *testing.T, p *giteaProvider) []string {
+	t.Helper()
+
+	var names []string
+	err := p.List(context.Background(), func(repo reposync.Repository) error {
+		names = append(names, repo.FullName)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return names
+}
+
+func TestListPaginates(t *testing.T) {
+	fake, client := newFakeGitea(t, "orgs/acme", true)
+
+	names := listNames(t, &giteaProvider{client: client, target: "acme", isOrg: true})
+	if got, want := strings.Join(names, ","), "acme/api,acme/web,acme/infra,acme/empty"; got != want {
+		t.Errorf("List() = %s, want %s without the mirror", got, want)
+	}
+	// The instance capped the page size, so the reported total is what stops the listing.
+	if got := strings.Join(fake.pages, ","); got != "1,2,3" {
+		t.Errorf("requested pages %s, want 1,2,3", got)
+	}
+}
+
+func TestListWithoutTotal(t *testing.T) {
+	fake, client := newFakeGitea(t, "users/acme", false)
+
+	names := listNames(t, &giteaProvider{client: client, target: "acme", includeMirrors: true})
+	if len(names) != 5 {
+		t.Errorf("List() = %v, want the 5 repositories including the mirror", names)
+	}
+	// Without X-Total-Count, listing stops on the first empty page.
+	if got := strings.Join(fake.pages, ","); got != "1,2,3,4" {
+		t.Errorf("requested pages %s, want 1,2,3,4", got)
+	}
+}
+
+func TestListRequiresToken(t *testing.T) {
+	_, client := newFakeGitea(t, "orgs/acme", true)
+	client.token = "wrong"
+
+	err := (&giteaProvider{client: client, target: "acme", isOrg: true}).List(context.Background(), func(reposync.Repository) error {
+		t.Error("no repository should be listed without a valid token")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("List() error = %v, want a 401 error", err)
+	}
+}
+
+func TestAuthUsesToken(t *testing.T) {
+	p := &giteaProvider{client: newGiteaClient("https://gitea.example.com", "secret")}
+
+	auth, ok := p.Auth(reposync.Repository{}).(*githttp.BasicAuth)
+	if !ok || auth.Username != "secret" {
+		t.Errorf("Auth() = %#v, want basic auth with the token as username", p.Auth(reposync.Repository{}))
+	}
+}
+
+func TestEmptyRepositoriesAreReported(t *testing.T) {
+	_, client := newFakeGitea(t, "orgs/acme", true)
+	syncer := reposync.New(&giteaProvider{client: client, target: "acme", isOrg: true}, reposync.Options{
+		BaseDir: t.TempDir(),
+		Prepare: func(ctx context.Context, task *reposync.Task) error {
+			if err := skipEmpty(ctx, task); err != nil {
+				return err
+			}
+			// Leave the others out too, so nothing is cloned from the fake instance.
+			return &reposync.Skip{Status: reposync.StatusUnchanged}
+		},
+	})
+
+	results, err := syncer.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := reposync.Count(results, reposync.StatusSkipped); got != 1 {
+		t.Fatalf("%d repositories skipped, want the empty one", got)
+	}
+	for _, res := range results {
+		if res.Status == reposync.StatusSkipped && (res.Task.FullName != "acme/empty" || res.Reason != "empty repository") {
+			t.Errorf("skipped %s: %q, want acme/empty as an empty repository", res.Task.FullName, res.Reason)
+		}
+	}
+}
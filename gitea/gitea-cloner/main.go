@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/Excoriate/dxutils/gitea/gitea-cloner/cloner"
+	"github.com/pterm/pterm"
+)
+
+func main() {
+	// The flag set exits on parse errors by itself.
+	_ = cloner.Flags().Parse(os.Args[1:])
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cloner.Run(ctx); err != nil {
+		pterm.Error.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,383 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-github/v42/github"
+	"github.com/pterm/pterm"
+	"golang.org/x/oauth2"
+)
+
+// repoInfo carries the GitHub specific values of a task between the sync hooks.
+type repoInfo struct {
+	// upstreamURL and upstreamBranch are set for forks, pointing at their parent repository.
+	upstreamURL    string
+	upstreamBranch string
+	// ref is the resolved -ref, empty to stay on the default branch.
+	ref plumbing.ReferenceName
+	// unchanged is set on forks that were not pushed since the last sync, to the reason they are skipped once
+	// their upstream is fetched.
+	unchanged string
+}
+
+// Statuses of the results specific to github-cloner, on top of the reposync ones.
+const (
+	statusOversized  reposync.Status = "oversized"
+	statusUnverified reposync.Status = "unverified"
+)
+
+var timeoutFlag = flags.String("timeout", "2m", "timeout duration for git operations")
+var totalBar *reposync.ProgressBar
+var defaultBranch = "main"
+var state *syncState
+var pages *pageCache
+var limits *sizeLimits
+
+const (
+	modeClone     = "clone"
+	modeInventory = "inventory"
+	modeServe     = "serve"
+	modePrune     = "prune-branches"
+)
+
+var modeFlag = flags.String("mode", modeClone, "what to do with the repositories: clone, inventory, serve or prune-branches")
+var baseDirFlag = flags.String("path", "./", "Path to clone repositories")
+var targetFlag = flags.String("target", "", "GitHub organization or user to clone from")
+var isOrgFlag = flags.Bool("org", false, "Specify if the target is an organization")
+var limitFlag = flags.Int("limit", 100, "Limit of repositories to clone")
+
+// flags holds every option of github-cloner. The standalone binary parses it from the command line, and
+// dxutils exposes it on its "clone github" command.
+var flags = flag.NewFlagSet("github-cloner", flag.ExitOnError)
+
+// ErrUnverified is returned by Run when the HEAD commit of some repositories is unsigned or signed by an unknown
+// key, so callers can report signature policy violations through a dedicated exit code.
+var ErrUnverified = errors.New("some repositories have an unsigned or unknown signed HEAD commit")
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run clones, inventories, serves or prunes the repositories of the target as selected by -mode.
+func Run(ctx context.Context) error {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return errors.New("GITHUB_TOKEN not set")
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	tc := oauth2.NewClient(ctx, ts)
+	gitHubClient := github.NewClient(tc)
+
+	if err := setupOutput(); err != nil {
+		return err
+	}
+
+	timeout, err := time.ParseDuration(*timeoutFlag)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration: %s", *timeoutFlag)
+	}
+
+	// Pruning works on the local clones only, so it needs no target.
+	if *modeFlag == modePrune {
+		if err := runPrune(ctx, *baseDirFlag, *isOrgFlag, timeout); err != nil {
+			return fmt.Errorf("failed to prune branches under %s: %w", *baseDirFlag, err)
+		}
+		return nil
+	}
+
+	if *targetFlag == "" {
+		return errors.New("please specify a target organization or user with the -target option")
+	}
+
+	switch *modeFlag {
+	case modeClone, modeServe:
+	case modeInventory:
+		if err := runInventory(ctx, gitHubClient, *targetFlag, *isOrgFlag, *limitFlag); err != nil {
+			return fmt.Errorf("failed to export inventory for %s: %w", *targetFlag, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q, expected clone, inventory, serve or prune-branches", *modeFlag)
+	}
+
+	if isInteractive() {
+		pterm.Info.Printf("Cloning projects from %s to %s\n", *targetFlag, *baseDirFlag)
+		pterm.Info.Println("RepoLimit set to", *limitFlag)
+	}
+
+	if *isOrgFlag {
+		logger.Debug("Cloning from an organization requires GITHUB_USERNAME and GITHUB_TOKEN to be set")
+		ghUserName := os.Getenv("GITHUB_USERNAME")
+		if ghUserName == "" {
+			return errors.New("GITHUB_USERNAME not set, and it's required when cloning from an organization")
+		}
+	}
+
+	if err := loadSparseConfig(*sparseConfigFlag); err != nil {
+		return fmt.Errorf("failed to load sparse config %s: %w", *sparseConfigFlag, err)
+	}
+
+	if *refFallbackFlag != refFallbackDefault && *refFallbackFlag != refFallbackSkip {
+		return fmt.Errorf("invalid ref fallback %q, expected default or skip", *refFallbackFlag)
+	}
+
+	if err := loadSigners(); err != nil {
+		return fmt.Errorf("failed to load signers: %w", err)
+	}
+
+	limits, err = parseSizeLimits()
+	if err != nil {
+		return err
+	}
+
+	statePath := *stateFlag
+	if statePath == "" {
+		statePath = filepath.Join(*baseDirFlag, defaultStateFile)
+	}
+	state, err = loadState(statePath)
+	if err != nil {
+		return fmt.Errorf("failed to load sync state %s: %w", statePath, err)
+	}
+	cachePath := filepath.Join(filepath.Dir(statePath), defaultCacheFile)
+	pages, err = loadPageCache(cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to load API cache %s: %w", cachePath, err)
+	}
+	tc.Transport = &etagTransport{base: tc.Transport, cache: pages, force: *forceFlag}
+
+	syncer := newSyncer(&gitHubProvider{client: gitHubClient, target: *targetFlag, isOrg: *isOrgFlag, limit: *limitFlag}, *baseDirFlag, timeout)
+
+	if *modeFlag == modeServe {
+		if err := runServe(ctx, syncer, *targetFlag, *baseDirFlag); err != nil {
+			return fmt.Errorf("serving %s failed: %w", *targetFlag, err)
+		}
+		return nil
+	}
+
+	progressStart("Cloning GitHub Repositories")
+	// Listing errors are already reported by the event reporter.
+	results, _ := syncer.Run(ctx)
+	progressStop()
+
+	if err := saveSyncState(); err != nil {
+		logFailure(statePath, 0, fmt.Errorf("failed to save sync state: %w", err))
+	}
+
+	if *workspaceFlag {
+		if err := generateWorkspaces(*baseDirFlag, *targetFlag); err != nil {
+			logFailure(*baseDirFlag, 0, fmt.Errorf("failed to generate workspaces: %w", err))
+		}
+	}
+
+	unverifiedTasks := reposync.Count(results, statusUnverified)
+	doneTasks := reposync.Count(results, reposync.StatusUpdated) + unverifiedTasks
+	unchangedTasks := reposync.Count(results, reposync.StatusUnchanged)
+	skippedTasks := reposync.Count(results, reposync.StatusSkipped)
+	oversizedTasks := reposync.Count(results, statusOversized)
+	failedTasks := reposync.Count(results, reposync.StatusFailed)
+	if isInteractive() {
+		pterm.Success.Printf("Cloned %d repositories from GitHub, %d unchanged, %d skipped, %d skipped because of size\n",
+			doneTasks, unchangedTasks, skippedTasks, oversizedTasks)
+	} else {
+		logEvent(eventSummary, *targetFlag, "total", len(results), "done", doneTasks, "unchanged", unchangedTasks,
+			"skipped", skippedTasks, "oversized", oversizedTasks, "unverified", unverifiedTasks, "failed", failedTasks)
+	}
+
+	if unverifiedTasks > 0 {
+		return fmt.Errorf("%w: %d repositories", ErrUnverified, unverifiedTasks)
+	}
+	return nil
+}
+
+// newSyncer wires the GitHub specific steps of a sync into the reposync hooks.
+func newSyncer(provider *gitHubProvider, baseDir string, timeout time.Duration) *reposync.Syncer {
+	return reposync.New(provider, reposync.Options{
+		BaseDir:        baseDir,
+		Timeout:        timeout,
+		FallbackBranch: defaultBranch,
+		GitProgress:    gitProgress(),
+		Reporter:       &eventReporter{target: provider.target},
+		Prepare:        provider.prepare,
+		Before:         beforeSync,
+		After:          finishSync,
+	})
+}
+
+func getReposByOrg(ctx context.Context, client *github.Client, org string, limit int) ([]*github.Repository, error) {
+	opt := &github.RepositoryListByOrgOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		Type:        "all",
+	}
+
+	var allRepos []*github.Repository
+	for {
+		repos, resp, err := client.Repositories.ListByOrg(ctx, org, opt)
+		if err != nil {
+			return nil, err
+		}
+		allRepos = append(allRepos, repos...)
+		if len(allRepos) >= limit || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	if len(allRepos) > limit {
+		allRepos = allRepos[:limit]
+	}
+	return allRepos, nil
+}
+
+func getReposByUser(ctx context.Context, client *github.Client, user string, limit int) ([]*github.Repository, error) {
+	opt := &github.RepositoryListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		Type:        "all",
+	}
+
+	var allRepos []*github.Repository
+	for {
+		repos, resp, err := client.Repositories.List(ctx, user, opt)
+		if err != nil {
+			return nil, err
+		}
+		allRepos = append(allRepos, repos...)
+		if len(allRepos) >= limit || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	if len(allRepos) > limit {
+		allRepos = allRepos[:limit]
+	}
+	return allRepos, nil
+}
+
+// saveSyncState persists the sync state and the API cache once a sync is over.
+func saveSyncState() error {
+	if err := state.save(); err != nil {
+		return err
+	}
+	return pages.save()
+}
+
+// syncedState is what the state file records for a successful sync of task with the current settings.
+func syncedState(task *reposync.Task) repoState {
+	return repoState{PushedAt: task.PushedAt, Ref: *refFlag, Sparse: task.Sparse, Upstream: taskInfo(task).upstreamURL}
+}
+
+// taskInfo returns the GitHub specific values of a task.
+func taskInfo(task *reposync.Task) *repoInfo {
+	if info, ok := task.Data.(*repoInfo); ok {
+		return info
+	}
+	return &repoInfo{}
+}
+
+// beforeSync fetches the upstream of unchanged forks, which moves on its own. Other repositories get their sparse
+// patterns cleared with -full-checkout, and -ref resolved.
+func beforeSync(ctx context.Context, task *reposync.Task) error {
+	if info := taskInfo(task); info.unchanged != "" {
+		if err := syncFork(ctx, *task); err != nil {
+			return err
+		}
+		return &reposync.Skip{Status: reposync.StatusUnchanged, Reason: info.unchanged}
+	}
+
+	if *fullCheckoutFlag && reposync.IsGitRepo(task.Dir) {
+		patterns, err := reposync.SparsePatterns(task.Dir)
+		if err == nil && len(patterns) > 0 {
+			err = reposync.ClearSparse(task.Dir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clear sparse patterns: %w", err)
+		}
+	}
+	return resolveTaskRef(ctx, task)
+}
+
+// resolveTaskRef resolves -ref before touching the worktree, so repositories without it can be skipped entirely.
+func resolveTaskRef(ctx context.Context, task *reposync.Task) error {
+	if *refFlag == "" {
+		return nil
+	}
+
+	ref, err := resolveRefWithTimeout(ctx, *task)
+	if err != nil {
+		return fmt.Errorf("failed to resolve ref %s: %w", *refFlag, err)
+	}
+	if ref == "" && *refFallbackFlag == refFallbackSkip {
+		return &reposync.Skip{Status: reposync.StatusSkipped, Reason: "ref " + *refFlag + " not found"}
+	}
+
+	// The registered task is shared with webhook syncs, so store the ref on a copy.
+	info := *taskInfo(task)
+	info.ref = ref
+	task.Data = &info
+	return nil
+}
+
+// finishSync syncs the upstream of forks, checks out -ref and verifies the HEAD signature of a repository that
+// was just cloned or pulled.
+func finishSync(ctx context.Context, task *reposync.Task, res *reposync.Result) error {
+	info := taskInfo(task)
+	if info.upstreamURL != "" {
+		if err := syncFork(ctx, *task); err != nil {
+			return err
+		}
+	}
+
+	res.Fields["ref"] = task.Branch
+	if info.ref != "" {
+		res.Fields["ref"] = info.ref.Short()
+		if err := checkoutRefWithTimeout(ctx, *task, info.ref); err != nil {
+			return err
+		}
+	}
+
+	var signature string
+	if *verifyFlag {
+		var err error
+		if signature, err = verifyHead(task.Dir); err != nil {
+			return err
+		}
+		res.Fields["signature"] = signature
+	}
+
+	// Unverified repositories are not recorded as synced, so the next run checks them again.
+	if *verifyFlag && signature != signatureVerified {
+		res.Status = statusUnverified
+		res.Err = fmt.Errorf("HEAD commit is %s", signature)
+		return nil
+	}
+
+	state.markSynced(task.FullName, syncedState(task))
+	return nil
+}
+
+// syncFork fetches the upstream of a fork within the timeout of the task, and reports how far its default branch
+// is behind.
+func syncFork(ctx context.Context, task reposync.Task) error {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	behind, err := syncUpstream(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to sync upstream: %w", err)
+	}
+
+	upstreamURL := taskInfo(&task).upstreamURL
+	logEvent(eventUpstream, task.Path, "upstream", upstreamURL, "behind", behind)
+	if isInteractive() && behind > 0 {
+		pterm.Info.Printf("%s is %d commits behind %s\n", task.Path, behind, upstreamURL)
+	}
+	return nil
+}
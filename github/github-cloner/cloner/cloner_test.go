@@ -0,0 +1,121 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// setFlag sets a command line flag for the duration of the test.
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+
+	previous := flags.Lookup(name).Value.String()
+	if err := flags.Set(name, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = flags.Set(name, previous) })
+}
+
+// setupTestState gives the test an empty sync state and a plain logger, as Run would.
+func setupTestState(t *testing.T) {
+	t.Helper()
+
+	setFlag(t, "output", outputPlain)
+	setFlag(t, "quiet", "true")
+	if err := setupOutput(); err != nil {
+		t.Fatal(err)
+	}
+	previous := state
+	state = &syncState{path: filepath.Join(t.TempDir(), defaultStateFile), Repos: map[string]repoState{}}
+	t.Cleanup(func() { state = previous })
+}
+
+// fakeProvider lists a fixed set of repositories.
+type fakeProvider struct {
+	repos []reposync.Repository
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	for _, repo := range p.repos {
+		if err := fn(repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakeProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return nil
+}
+
+// newOrigin creates a repository with a single commit on master under dir, and returns it with its path.
+func newOrigin(t *testing.T, dir, name string) (*git.Repository, string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	r, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, r, path, "README.md", "# "+name)
+	return r, path
+}
+
+// commitFile writes a file in the worktree of r at path and commits it on the current branch.
+func commitFile(t *testing.T, r *git.Repository, path, file, content string) plumbing.Hash {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(path, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	hash, err := w.Commit("update "+file, &git.CommitOptions{Author: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// switchBranch checks out branch in the worktree of r, creating it on the current commit if create is set.
+func switchBranch(t *testing.T, r *git.Repository, branch string, create bool) {
+	t.Helper()
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// refHash returns the commit a reference of r points at.
+func refHash(t *testing.T, r *git.Repository, name plumbing.ReferenceName) plumbing.Hash {
+	t.Helper()
+
+	ref, err := r.Reference(name, true)
+	if err != nil {
+		t.Fatalf("reference %s: %v", name, err)
+	}
+	return ref.Hash()
+}
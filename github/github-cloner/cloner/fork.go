@@ -0,0 +1,118 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v42/github"
+)
+
+const upstreamRemote = "upstream"
+
+// gitAuth returns the credentials used for organization repositories, or nil for public user repositories.
+func gitAuth(isOrg bool) transport.AuthMethod {
+	if !isOrg {
+		return nil
+	}
+	return &http.BasicAuth{
+		Username: os.Getenv("GITHUB_USERNAME"),
+		Password: os.Getenv("GITHUB_TOKEN"),
+	}
+}
+
+// forkParent fetches the full repository, since the listing endpoints don't include the parent of a fork.
+func forkParent(ctx context.Context, client *github.Client, repo *github.Repository) (*github.Repository, error) {
+	full, _, err := client.Repositories.Get(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if full.GetParent() == nil {
+		return nil, fmt.Errorf("fork %s has no parent", repo.GetFullName())
+	}
+	return full.GetParent(), nil
+}
+
+// syncUpstream ensures the upstream remote of a fork exists and is fetched, and returns how many commits the
+// fork's default branch is behind the upstream default branch. The fetch is aborted once ctx is done.
+func syncUpstream(ctx context.Context, task reposync.Task) (int, error) {
+	info := taskInfo(&task)
+	r, err := git.PlainOpen(task.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	remote, err := r.Remote(upstreamRemote)
+	switch {
+	case errors.Is(err, git.ErrRemoteNotFound):
+		_, err = r.CreateRemote(&config.RemoteConfig{Name: upstreamRemote, URLs: []string{info.upstreamURL}})
+	case err == nil && remote.Config().URLs[0] != info.upstreamURL:
+		// The parent may have been renamed or transferred, so keep the remote pointing at it.
+		if err = r.DeleteRemote(upstreamRemote); err == nil {
+			_, err = r.CreateRemote(&config.RemoteConfig{Name: upstreamRemote, URLs: []string{info.upstreamURL}})
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: upstreamRemote,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/upstream/*"},
+		Force:      true,
+		Auth:       task.Auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return 0, err
+	}
+
+	return commitsBehind(r,
+		plumbing.NewRemoteReferenceName("origin", task.Branch),
+		plumbing.NewRemoteReferenceName(upstreamRemote, info.upstreamBranch))
+}
+
+// commitsBehind counts the commits reachable from upstream that are not reachable from local.
+func commitsBehind(r *git.Repository, local, upstream plumbing.ReferenceName) (int, error) {
+	localRef, err := r.Reference(local, true)
+	if err != nil {
+		return 0, err
+	}
+	upstreamRef, err := r.Reference(upstream, true)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := map[plumbing.Hash]bool{}
+	localLog, err := r.Log(&git.LogOptions{From: localRef.Hash()})
+	if err != nil {
+		return 0, err
+	}
+	err = localLog.ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	behind := 0
+	upstreamLog, err := r.Log(&git.LogOptions{From: upstreamRef.Hash()})
+	if err != nil {
+		return 0, err
+	}
+	err = upstreamLog.ForEach(func(c *object.Commit) error {
+		if !seen[c.Hash] {
+			behind++
+		}
+		return nil
+	})
+	return behind, err
+}
@@ -0,0 +1,45 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+)
+
+func TestSyncUpstream(t *testing.T) {
+	dir := t.TempDir()
+	upstream, upstreamPath := newOrigin(t, dir, "upstream")
+	clonePath := filepath.Join(dir, "fork")
+	if _, err := git.PlainClone(clonePath, false, &git.CloneOptions{URL: upstreamPath}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, upstream, upstreamPath, "main.go", "package main")
+	commitFile(t, upstream, upstreamPath, "go.mod", "module upstream")
+
+	task := reposync.Task{
+		Repository: reposync.Repository{Path: "fork"},
+		Dir:        clonePath,
+		Branch:     "master",
+		Data:       &repoInfo{upstreamURL: upstreamPath, upstreamBranch: "master"},
+	}
+
+	behind, err := syncUpstream(context.Background(), task)
+	if err != nil {
+		t.Fatalf("syncUpstream() error = %v", err)
+	}
+	if behind != 2 {
+		t.Errorf("syncUpstream() = %d commits behind, want 2", behind)
+	}
+
+	// The fetch stops with the context, rather than going on in the background.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	commitFile(t, upstream, upstreamPath, "README.md", "# moved on")
+	if _, err := syncUpstream(ctx, task); !errors.Is(err, context.Canceled) {
+		t.Errorf("syncUpstream() error = %v with a cancelled context, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,179 @@
+package cloner
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+)
+
+const (
+	formatCSV      = "csv"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+)
+
+var formatFlag = flags.String("format", formatCSV, "inventory format: csv, json or markdown")
+var outFlag = flags.String("out", "", "inventory output file (defaults to stdout)")
+
+// inventoryItem is one row of the repository inventory.
+type inventoryItem struct {
+	Name            string    `json:"name"`
+	Visibility      string    `json:"visibility"`
+	DefaultBranch   string    `json:"default_branch"`
+	BranchProtected *bool     `json:"default_branch_protected"`
+	Topics          []string  `json:"topics"`
+	Language        string    `json:"language"`
+	SizeKB          int       `json:"size_kb"`
+	Archived        bool      `json:"archived"`
+	LastPush        time.Time `json:"last_push"`
+	OpenIssues      int       `json:"open_issues"`
+}
+
+var inventoryHeader = []string{
+	"name", "visibility", "default_branch", "default_branch_protected", "topics",
+	"language", "size_kb", "archived", "last_push", "open_issues",
+}
+
+func (i inventoryItem) row() []string {
+	protected := ""
+	if i.BranchProtected != nil {
+		protected = strconv.FormatBool(*i.BranchProtected)
+	}
+	lastPush := ""
+	if !i.LastPush.IsZero() {
+		lastPush = i.LastPush.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		i.Name, i.Visibility, i.DefaultBranch, protected, strings.Join(i.Topics, " "),
+		i.Language, strconv.Itoa(i.SizeKB), strconv.FormatBool(i.Archived), lastPush, strconv.Itoa(i.OpenIssues),
+	}
+}
+
+// runInventory lists the repositories of target and writes their inventory without cloning anything.
+func runInventory(ctx context.Context, client *github.Client, target string, isOrg bool, limit int) error {
+	switch *formatFlag {
+	case formatCSV, formatJSON, formatMarkdown:
+	default:
+		return fmt.Errorf("invalid format %q, expected csv, json or markdown", *formatFlag)
+	}
+	if *outFlag == "" {
+		// Keep stdout for the inventory itself.
+		logger = logger.WithWriter(os.Stderr)
+	}
+
+	start := time.Now()
+	var repos []*github.Repository
+	var err error
+	if isOrg {
+		repos, err = getReposByOrg(ctx, client, target, limit)
+	} else {
+		repos, err = getReposByUser(ctx, client, target, limit)
+	}
+	if err != nil {
+		return err
+	}
+	logEvent(eventListed, target, "count", len(repos), "duration", roundDuration(time.Since(start)))
+
+	items := make([]inventoryItem, 0, len(repos))
+	for _, repo := range repos {
+		items = append(items, inventoryItemFor(ctx, client, repo))
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *formatFlag {
+	case formatJSON:
+		return writeInventoryJSON(w, items)
+	case formatMarkdown:
+		return writeInventoryMarkdown(w, items)
+	default:
+		return writeInventoryCSV(w, items)
+	}
+}
+
+// inventoryItemFor maps a repository to an inventory row, looking up the protection of its default branch.
+func inventoryItemFor(ctx context.Context, client *github.Client, repo *github.Repository) inventoryItem {
+	visibility := repo.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if repo.GetPrivate() {
+			visibility = "private"
+		}
+	}
+
+	item := inventoryItem{
+		Name:          repo.GetFullName(),
+		Visibility:    visibility,
+		DefaultBranch: repo.GetDefaultBranch(),
+		Topics:        repo.Topics,
+		Language:      repo.GetLanguage(),
+		SizeKB:        repo.GetSize(),
+		Archived:      repo.GetArchived(),
+		LastPush:      repo.GetPushedAt().Time,
+		OpenIssues:    repo.GetOpenIssuesCount(),
+	}
+
+	// Empty repositories have no default branch yet, so their protection is left unknown.
+	if item.DefaultBranch == "" {
+		return item
+	}
+	branch, _, err := client.Repositories.GetBranch(ctx, repo.GetOwner().GetLogin(), repo.GetName(), item.DefaultBranch, true)
+	if err != nil {
+		logger.Debug("failed to get default branch", logger.Args("repo", item.Name, "error", err.Error()))
+		return item
+	}
+	protected := branch.GetProtected()
+	item.BranchProtected = &protected
+	return item
+}
+
+func writeInventoryCSV(w io.Writer, items []inventoryItem) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(inventoryHeader); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := cw.Write(item.row()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeInventoryJSON(w io.Writer, items []inventoryItem) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+func writeInventoryMarkdown(w io.Writer, items []inventoryItem) error {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(inventoryHeader, " | ") + " |\n")
+	b.WriteString(strings.Repeat("|---", len(inventoryHeader)) + "|\n")
+	for _, item := range items {
+		row := item.row()
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "|", "\\|")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
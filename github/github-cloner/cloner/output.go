@@ -0,0 +1,210 @@
+package cloner
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/pterm/pterm"
+)
+
+const (
+	outputInteractive = "interactive"
+	outputPlain       = "plain"
+	outputJSON        = "json"
+)
+
+// Lifecycle events emitted for every repository.
+const (
+	eventListed     = "listed"
+	eventQueued     = "queued"
+	eventCloning    = "cloning"
+	eventUnchanged  = "unchanged"
+	eventUpstream   = "upstream"
+	eventSkipped    = "skipped"
+	eventUnverified = "unverified"
+	eventPruned     = "pruned"
+	eventKept       = "kept"
+	eventUpdated    = "updated"
+	eventFailed     = "failed"
+	eventSummary    = "summary"
+)
+
+var outputFlag = flags.String("output", outputInteractive, "output mode: interactive, plain or json")
+var quietFlag = flags.Bool("quiet", false, "only print failures")
+var verboseFlag = flags.Bool("verbose", false, "print debug events")
+var logger *pterm.Logger
+
+// setupOutput validates the output flags and configures the logger and progress bar accordingly.
+func setupOutput() error {
+	if *quietFlag && *verboseFlag {
+		return fmt.Errorf("-quiet and -verbose are mutually exclusive")
+	}
+
+	l := pterm.DefaultLogger.WithLevel(logLevel())
+	switch *outputFlag {
+	case outputInteractive:
+		logger = l
+	case outputPlain:
+		pterm.DisableStyling()
+		logger = l.WithMaxWidth(math.MaxInt32)
+	case outputJSON:
+		pterm.DisableStyling()
+		logger = l.WithFormatter(pterm.LogFormatterJSON)
+	default:
+		return fmt.Errorf("invalid output mode %q, expected interactive, plain or json", *outputFlag)
+	}
+
+	return nil
+}
+
+// logLevel maps -quiet and -verbose to a logger level. In interactive mode the progress bar already shows
+// what is going on, so lifecycle events are only printed when -verbose is set.
+func logLevel() pterm.LogLevel {
+	switch {
+	case *quietFlag:
+		return pterm.LogLevelError
+	case *verboseFlag:
+		return pterm.LogLevelDebug
+	case isInteractive():
+		return pterm.LogLevelWarn
+	default:
+		return pterm.LogLevelInfo
+	}
+}
+
+func isInteractive() bool {
+	return *outputFlag == outputInteractive
+}
+
+// gitProgress returns the writer used for raw git progress, which is only shown in interactive mode.
+func gitProgress() io.Writer {
+	if !isInteractive() || *quietFlag {
+		return nil
+	}
+	return os.Stdout
+}
+
+// logEvent emits one structured lifecycle event for a repository.
+func logEvent(event, repo string, args ...any) {
+	logAt(pterm.LogLevelInfo, event, repo, args...)
+}
+
+// logFailure emits a failed event, carrying the error and the time spent on the repository.
+func logFailure(repo string, elapsed time.Duration, err error) {
+	logAt(pterm.LogLevelError, eventFailed, repo, "duration", roundDuration(elapsed), "error", err.Error())
+}
+
+func logAt(level pterm.LogLevel, event, repo string, args ...any) {
+	fields := logger.Args(append([]any{"event", event, "repo", repo}, args...)...)
+	switch level {
+	case pterm.LogLevelDebug:
+		logger.Debug(event, fields)
+	case pterm.LogLevelError:
+		logger.Error(event, fields)
+	default:
+		logger.Info(event, fields)
+	}
+}
+
+func roundDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// The progress helpers below are no-ops outside interactive mode, so CI logs stay line oriented.
+
+func progressStart(title string) {
+	if !isInteractive() || *quietFlag {
+		return
+	}
+	totalBar = reposync.NewProgressBar(title)
+}
+
+func progressQueued() {
+	if totalBar != nil {
+		totalBar.Queued()
+	}
+}
+
+func progressListed() {
+	if totalBar != nil {
+		totalBar.Listed()
+	}
+}
+
+func progressTitle(title string) {
+	if totalBar != nil {
+		totalBar.Title(title)
+	}
+}
+
+func progressIncrement() {
+	if totalBar != nil {
+		totalBar.Increment()
+	}
+}
+
+func progressStop() {
+	if totalBar != nil {
+		totalBar.Stop()
+		totalBar = nil
+	}
+}
+
+// eventReporter turns the progress of a reposync.Syncer into lifecycle events, progress bar updates and status
+// page entries.
+type eventReporter struct {
+	target string
+
+	mu sync.Mutex
+}
+
+func (r *eventReporter) Listed(count int, elapsed time.Duration, err error) {
+	progressListed()
+	if err != nil {
+		logFailure(r.target, elapsed, fmt.Errorf("failed to list repositories: %w", err))
+		return
+	}
+	logEvent(eventListed, r.target, "count", count, "duration", roundDuration(elapsed))
+}
+
+func (r *eventReporter) Queued(task reposync.Task) {
+	progressQueued()
+}
+
+func (r *eventReporter) Started(task reposync.Task, action reposync.Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progressTitle("Cloning " + task.CloneURL)
+	logEvent(eventCloning, task.Path, "action", action, "path", task.Dir)
+}
+
+func (r *eventReporter) Finished(res reposync.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progressIncrement()
+	ref, _ := res.Fields["ref"].(string)
+	switch res.Status {
+	case reposync.StatusUpdated:
+		fields := []any{"action", res.Action, "ref", ref, "duration", roundDuration(res.Duration)}
+		if signature, ok := res.Fields["signature"]; ok {
+			fields = append(fields, "signature", signature)
+		}
+		logEvent(eventUpdated, res.Task.Path, fields...)
+	case statusUnverified:
+		logAt(pterm.LogLevelError, eventUnverified, res.Task.Path, "ref", ref, "signature", res.Fields["signature"])
+	case reposync.StatusUnchanged:
+		logEvent(eventUnchanged, res.Task.Path, "pushed_at", res.Task.PushedAt)
+	case reposync.StatusSkipped, statusOversized:
+		logEvent(eventSkipped, res.Task.Path, "reason", res.Reason)
+	default:
+		logFailure(res.Task.Path, res.Duration, res.Err)
+	}
+	recordResult(res)
+}
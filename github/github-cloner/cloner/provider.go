@@ -0,0 +1,108 @@
+package cloner
+
+import (
+	"context"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/go-github/v42/github"
+)
+
+// gitHubProvider lists the repositories of an organization or a user.
+type gitHubProvider struct {
+	client *github.Client
+	target string
+	isOrg  bool
+	limit  int
+
+	// repos keeps the listed repositories by full name, for the hooks that need more than reposync.Repository.
+	repos map[string]*github.Repository
+}
+
+func (p *gitHubProvider) Name() string {
+	return "GitHub"
+}
+
+// List fetches every page before calling fn, since -order needs all the sizes to sort the repositories.
+func (p *gitHubProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	var allRepos []*github.Repository
+	var err error
+	if p.isOrg {
+		allRepos, err = getReposByOrg(ctx, p.client, p.target, p.limit)
+	} else {
+		allRepos, err = getReposByUser(ctx, p.client, p.target, p.limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	orderRepos(allRepos)
+	limits.usedKB = 0
+
+	p.repos = make(map[string]*github.Repository, len(allRepos))
+	for _, repo := range allRepos {
+		p.repos[repo.GetFullName()] = repo
+		if err := fn(repoRepository(repo)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *gitHubProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return gitAuth(p.isOrg)
+}
+
+func repoRepository(repo *github.Repository) reposync.Repository {
+	return reposync.Repository{
+		Path:          repo.GetName(),
+		FullName:      repo.GetFullName(),
+		CloneURL:      repo.GetCloneURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Archived:      repo.GetArchived(),
+		SizeKB:        int64(repo.GetSize()),
+		PushedAt:      repo.GetPushedAt().Time,
+	}
+}
+
+// prepare applies the size limits, sparse directories and incremental sync state to a listed repository, and
+// looks up the parent of forks.
+func (p *gitHubProvider) prepare(ctx context.Context, task *reposync.Task) error {
+	task.Sparse = sparseFor(task.Path, task.FullName)
+	task.Timeout = limits.timeout(task.Timeout, task.SizeKB)
+
+	// Repositories that don't fit the size limits are never cloned nor updated.
+	if reason := limits.admit(task.SizeKB); reason != "" {
+		return &reposync.Skip{Status: statusOversized, Reason: reason}
+	}
+
+	info := &repoInfo{}
+	if repo := p.repos[task.FullName]; repo != nil && repo.GetFork() {
+		parent, err := forkParent(ctx, p.client, repo)
+		if err != nil {
+			logger.Warn("failed to get fork parent", logger.Args("repo", task.Path, "error", err.Error()))
+		} else {
+			info.upstreamURL = parent.GetCloneURL()
+			info.upstreamBranch = parent.GetDefaultBranch()
+		}
+	}
+	task.Data = info
+	registerTask(*task)
+
+	// Skip repositories that were not pushed since the last successful sync with the same settings. Forks are
+	// still queued, to fetch their upstream.
+	if !*forceFlag && reposync.IsGitRepo(task.Dir) && state.unchanged(task.FullName, syncedState(task)) {
+		reason := "not pushed since " + task.PushedAt.Format(time.RFC3339)
+		if info.upstreamURL == "" {
+			return &reposync.Skip{Status: reposync.StatusUnchanged, Reason: reason}
+		}
+		// The registered task must still sync the fork entirely when a webhook triggers it.
+		unchanged := *info
+		unchanged.unchanged = reason
+		task.Data = &unchanged
+	}
+
+	logEvent(eventQueued, task.Path, "branch", task.Branch)
+	return nil
+}
@@ -0,0 +1,258 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pterm/pterm"
+)
+
+var dryRunFlag = flags.Bool("dry-run", false, "only report the branches prune-branches would delete")
+
+// runPrune deletes, in every clone under baseDir, the local branches that were merged into the default branch
+// or whose upstream was deleted. The current branch, the default branch and branches with unpushed commits are
+// always kept. The network operations of each clone are aborted after timeout, and pruning stops with ctx.
+func runPrune(ctx context.Context, baseDir string, isOrg bool, timeout time.Duration) error {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return err
+	}
+
+	pruned, kept := 0, 0
+	for _, entry := range entries {
+		path := filepath.Join(baseDir, entry.Name())
+		if !entry.IsDir() || !reposync.IsGitRepo(path) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		repoCtx, cancel := context.WithTimeout(ctx, timeout)
+		p, k, err := pruneBranches(repoCtx, path, isOrg)
+		cancel()
+		if err != nil {
+			logFailure(entry.Name(), time.Since(start), err)
+			continue
+		}
+		pruned += p
+		kept += k
+	}
+
+	verb := "Deleted"
+	if *dryRunFlag {
+		verb = "Would delete"
+	}
+	if isInteractive() {
+		pterm.Success.Printf("%s %d branches, kept %d\n", verb, pruned, kept)
+		return nil
+	}
+	logEvent(eventSummary, baseDir, "pruned", pruned, "kept", kept, "dry_run", *dryRunFlag)
+	return nil
+}
+
+// pruneBranches prunes the local branches of a single clone, returning how many were pruned and kept.
+func pruneBranches(ctx context.Context, path string, isOrg bool) (int, int, error) {
+	repoName := filepath.Base(path)
+	r, err := git.PlainOpen(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = r.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Force:      true,
+		Auth:       gitAuth(isOrg),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return 0, 0, err
+	}
+
+	defaultRef, stale, err := pruneRemoteRefs(ctx, r, repoName, isOrg)
+	if err != nil {
+		return 0, 0, err
+	}
+	defaultCommit, err := r.CommitObject(defaultRef.Hash())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	remoteCommits, err := remoteTips(r, stale)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return 0, 0, err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	branches, err := r.Branches()
+	if err != nil {
+		return 0, 0, err
+	}
+	var local []*plumbing.Reference
+	_ = branches.ForEach(func(ref *plumbing.Reference) error {
+		local = append(local, ref)
+		return nil
+	})
+
+	// HEAD may be off the default branch, e.g. after a -ref checkout, and the local default branch is then
+	// usually merged into its remote-tracking one. It's kept all the same, as syncs check it out again.
+	defaultBranch := plumbing.NewBranchReferenceName(strings.TrimPrefix(defaultRef.Name().String(), "refs/remotes/origin/"))
+
+	pruned, kept := 0, 0
+	for _, ref := range local {
+		name := ref.Name().Short()
+		if ref.Name() == head.Name() || ref.Name() == defaultBranch {
+			continue
+		}
+
+		tip, err := r.CommitObject(ref.Hash())
+		if err != nil {
+			return pruned, kept, err
+		}
+
+		reason := ""
+		if merged, _ := tip.IsAncestor(defaultCommit); merged {
+			reason = "merged into " + defaultRef.Name().Short()
+		} else if b, ok := cfg.Branches[name]; ok && b.Merge != "" {
+			upstream := plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short())
+			if _, err := r.Reference(upstream, false); errors.Is(err, plumbing.ErrReferenceNotFound) || stale[upstream] {
+				reason = "upstream " + upstream.Short() + " was deleted"
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		if !containedInRemote(tip, remoteCommits) {
+			kept++
+			logEvent(eventKept, repoName, "branch", name, "reason", "unpushed commits")
+			continue
+		}
+
+		pruned++
+		logEvent(eventPruned, repoName, "branch", name, "reason", reason, "dry_run", *dryRunFlag)
+		if *dryRunFlag {
+			continue
+		}
+		if err := r.Storer.RemoveReference(ref.Name()); err != nil {
+			return pruned, kept, err
+		}
+		if err := r.DeleteBranch(name); err != nil && !errors.Is(err, git.ErrBranchNotFound) {
+			return pruned, kept, err
+		}
+	}
+
+	if isInteractive() && pruned > 0 {
+		pterm.Info.Printf("%s: %d branches pruned\n", repoName, pruned)
+	}
+	return pruned, kept, nil
+}
+
+// pruneRemoteRefs finds the remote-tracking branches whose branch was deleted on origin, since go-git can't prune
+// while fetching, and removes them unless -dry-run is set. It returns origin/<default>, as advertised by the
+// remote HEAD and falling back to main or master, and the stale refs, which callers must treat as missing.
+func pruneRemoteRefs(ctx context.Context, r *git.Repository, repoName string, isOrg bool) (*plumbing.Reference, map[plumbing.ReferenceName]bool, error) {
+	candidates := []string{"main", "master"}
+
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return nil, nil, err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: gitAuth(isOrg)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	heads := map[string]bool{}
+	for _, ref := range refs {
+		if ref.Name().IsBranch() {
+			heads[ref.Name().Short()] = true
+		}
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			candidates = append([]string{ref.Target().Short()}, candidates...)
+		}
+	}
+
+	tracking, err := r.References()
+	if err != nil {
+		return nil, nil, err
+	}
+	stale := map[plumbing.ReferenceName]bool{}
+	_ = tracking.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if strings.HasPrefix(name, "refs/remotes/origin/") && ref.Type() == plumbing.HashReference &&
+			!heads[strings.TrimPrefix(name, "refs/remotes/origin/")] {
+			stale[ref.Name()] = true
+		}
+		return nil
+	})
+	for name := range stale {
+		logEvent(eventPruned, repoName, "ref", name.Short(), "reason", "deleted on origin", "dry_run", *dryRunFlag)
+		if *dryRunFlag {
+			continue
+		}
+		if err := r.Storer.RemoveReference(name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, name := range candidates {
+		ref, err := r.Reference(plumbing.NewRemoteReferenceName("origin", name), true)
+		if err == nil && !stale[ref.Name()] {
+			return ref, stale, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("can't resolve the default branch of origin")
+}
+
+// remoteTips returns the commits of the remote-tracking branches, leaving out the stale ones.
+func remoteTips(r *git.Repository, stale map[plumbing.ReferenceName]bool) ([]*object.Commit, error) {
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var tips []*object.Commit
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference || stale[ref.Name()] {
+			return nil
+		}
+		c, err := r.CommitObject(ref.Hash())
+		if err != nil {
+			return nil // Remote refs pointing to something else than a commit are irrelevant here.
+		}
+		tips = append(tips, c)
+		return nil
+	})
+	return tips, err
+}
+
+// containedInRemote reports if every commit of tip is reachable from a remote-tracking branch.
+func containedInRemote(tip *object.Commit, remotes []*object.Commit) bool {
+	for _, remote := range remotes {
+		if ok, _ := tip.IsAncestor(remote); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package cloner
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newPruneFixture clones an origin whose master moved on, and sets up local branches covering every pruning
+// case:
+//   - merged, behind origin/master, is pruned;
+//   - gone, whose upstream was deleted on origin but whose commits are still on origin/other, is pruned;
+//   - unpushed, tracking the deleted branch with a commit of its own, is kept;
+//   - current, merged but checked out, is left alone;
+//   - master, the default branch, is behind origin/master but left alone too.
+func newPruneFixture(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+
+	dir := t.TempDir()
+	origin, originPath := newOrigin(t, dir, "origin")
+	first := refHash(t, origin, plumbing.HEAD)
+	switchBranch(t, origin, "gone", true)
+	gone := commitFile(t, origin, originPath, "gone.txt", "gone")
+	switchBranch(t, origin, "other", true)
+	switchBranch(t, origin, "master", false)
+	commitFile(t, origin, originPath, "main.go", "package main")
+
+	clonePath := filepath.Join(dir, "clone")
+	r, err := git.PlainClone(clonePath, false, &git.CloneOptions{URL: originPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, hash := range map[string]plumbing.Hash{"merged": first, "gone": gone, "unpushed": gone, "current": first} {
+		if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"gone", "unpushed"} {
+		if err := r.CreateBranch(&config.Branch{Name: name, Remote: "origin", Merge: plumbing.NewBranchReferenceName("gone")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	switchBranch(t, r, "unpushed", false)
+	commitFile(t, r, clonePath, "unpushed.txt", "unpushed")
+	switchBranch(t, r, "current", false)
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), first)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := origin.Storer.RemoveReference(plumbing.NewBranchReferenceName("gone")); err != nil {
+		t.Fatal(err)
+	}
+	return clonePath, r
+}
+
+// localBranches returns the sorted names of the local branches and the remote-tracking ones of r.
+func localBranches(t *testing.T, r *git.Repository) string {
+	t.Helper()
+
+	refs, err := r.References()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() || ref.Name().IsRemote() {
+			names = append(names, ref.Name().Short())
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestPruneBranches(t *testing.T) {
+	setupTestState(t)
+	clonePath, r := newPruneFixture(t)
+
+	pruned, kept, err := pruneBranches(context.Background(), clonePath, false)
+	if err != nil {
+		t.Fatalf("pruneBranches() error = %v", err)
+	}
+	if pruned != 2 || kept != 1 {
+		t.Errorf("pruneBranches() = %d pruned, %d kept, want 2 pruned and 1 kept", pruned, kept)
+	}
+	want := "current,master,origin/master,origin/other,unpushed"
+	if got := localBranches(t, r); got != want {
+		t.Errorf("branches left %s, want %s", got, want)
+	}
+}
+
+func TestPruneBranchesDryRun(t *testing.T) {
+	setupTestState(t)
+	setFlag(t, "dry-run", "true")
+	clonePath, r := newPruneFixture(t)
+	before := localBranches(t, r)
+
+	pruned, kept, err := pruneBranches(context.Background(), clonePath, false)
+	if err != nil {
+		t.Fatalf("pruneBranches() error = %v", err)
+	}
+	if pruned != 2 || kept != 1 {
+		t.Errorf("pruneBranches() = %d pruned, %d kept, want 2 pruned and 1 kept", pruned, kept)
+	}
+	if got := localBranches(t, r); got != before {
+		t.Errorf("branches changed to %s with -dry-run, want %s", got, before)
+	}
+}
+
+func TestRunPruneStopsWhenCancelled(t *testing.T) {
+	setupTestState(t)
+	clonePath, r := newPruneFixture(t)
+	before := localBranches(t, r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runPrune(ctx, filepath.Dir(clonePath), false, time.Minute); err != context.Canceled {
+		t.Errorf("runPrune() error = %v, want context.Canceled", err)
+	}
+	if got := localBranches(t, r); got != before {
+		t.Errorf("branches changed to %s after cancellation, want %s", got, before)
+	}
+}
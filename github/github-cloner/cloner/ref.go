@@ -0,0 +1,161 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+const (
+	refFallbackDefault = "default"
+	refFallbackSkip    = "skip"
+)
+
+var refFlag = flags.String("ref", "", "branch or tag to check out in every repository, globs like v1.* resolve to the highest match")
+var refFallbackFlag = flags.String("ref-fallback", refFallbackDefault, "what to do when -ref is not found: default or skip")
+
+// resolveRefWithTimeout lists the remote references of the repository and resolves -ref against them, within the
+// timeout of the task. An empty reference name is returned when nothing matches.
+func resolveRefWithTimeout(ctx context.Context, task reposync.Task) (plumbing.ReferenceName, error) {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{task.CloneURL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: task.Auth})
+	if err != nil {
+		return "", err
+	}
+
+	var names []plumbing.ReferenceName
+	for _, ref := range refs {
+		if ref.Name().IsBranch() || ref.Name().IsTag() {
+			names = append(names, ref.Name())
+		}
+	}
+	return matchRef(names, *refFlag), nil
+}
+
+// matchRef picks the reference matching pattern. Exact branch names win over tags, and glob patterns resolve to
+// the highest version among the matching names.
+func matchRef(names []plumbing.ReferenceName, pattern string) plumbing.ReferenceName {
+	if !strings.ContainsAny(pattern, "*?[") {
+		for _, full := range []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(pattern),
+			plumbing.NewTagReferenceName(pattern),
+		} {
+			for _, name := range names {
+				if name == full {
+					return name
+				}
+			}
+		}
+		return ""
+	}
+
+	var best plumbing.ReferenceName
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name.Short()); !ok {
+			continue
+		}
+		if best == "" || compareVersions(name.Short(), best.Short()) > 0 {
+			best = name
+		}
+	}
+	return best
+}
+
+// compareVersions compares two names as dotted versions, ignoring any non-numeric prefix such as "v" or
+// "release/". Numeric parts are compared numerically and any other part lexically.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+func versionParts(name string) []string {
+	name = strings.TrimLeftFunc(name, func(r rune) bool { return r < '0' || r > '9' })
+	return strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+}
+
+// checkoutRefWithTimeout checks out the resolved reference in the worktree of an already synced repository, within
+// the timeout of the task.
+func checkoutRefWithTimeout(ctx context.Context, task reposync.Task, ref plumbing.ReferenceName) error {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	if err := checkoutRef(ctx, task, ref); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", ref.Short(), err)
+	}
+	return nil
+}
+
+// checkoutRef checks out ref, fetching it first if it's a tag. The fetch is aborted once ctx is done.
+func checkoutRef(ctx context.Context, task reposync.Task, ref plumbing.ReferenceName) error {
+	r, err := git.PlainOpen(task.Dir)
+	if err != nil {
+		return err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// Branches are already fetched under refs/remotes/origin, tags are fetched on demand.
+	local := plumbing.NewRemoteReferenceName("origin", ref.Short())
+	if ref.IsTag() {
+		local = ref
+		err = r.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + ref)},
+			Force:      true,
+			Auth:       task.Auth,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(local))
+	if err != nil {
+		return err
+	}
+
+	patterns, err := reposync.SparsePatterns(task.Dir)
+	if err != nil {
+		return err
+	}
+
+	// The checkout only moves HEAD, the sparse reset then updates the worktree without materialising it entirely.
+	opts := &git.CheckoutOptions{Branch: ref, Keep: true}
+	if ref.IsTag() {
+		opts = &git.CheckoutOptions{Hash: *hash, Keep: true}
+	} else if _, err := r.Reference(ref, false); err != nil {
+		opts.Create = true
+		opts.Hash = *hash
+	}
+	if err := w.Checkout(opts); err != nil {
+		return err
+	}
+	return reposync.ResetSparse(r, *hash, patterns)
+}
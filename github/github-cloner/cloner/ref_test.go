@@ -0,0 +1,146 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestMatchRef(t *testing.T) {
+	names := []plumbing.ReferenceName{
+		"refs/heads/master",
+		"refs/heads/release",
+		"refs/tags/release",
+		"refs/tags/v1.2.0",
+		"refs/tags/v1.10.0",
+		"refs/tags/v2.0.0-rc1",
+	}
+	tests := []struct {
+		pattern string
+		want    plumbing.ReferenceName
+	}{
+		{"release", "refs/heads/release"},
+		{"v1.2.0", "refs/tags/v1.2.0"},
+		{"v1.*", "refs/tags/v1.10.0"},
+		{"v*", "refs/tags/v2.0.0-rc1"},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if got := matchRef(names, tt.pattern); got != tt.want {
+			t.Errorf("matchRef(%q) = %s, want %s", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSyncWithoutRefReturnsToDefaultBranch(t *testing.T) {
+	setupTestState(t)
+	origins, base := t.TempDir(), t.TempDir()
+
+	// release branches off master, which then moves on.
+	origin, originPath := newOrigin(t, origins, "api")
+	switchBranch(t, origin, "release", true)
+	commitFile(t, origin, originPath, "release.txt", "release")
+	switchBranch(t, origin, "master", false)
+	commitFile(t, origin, originPath, "main.go", "package main")
+	master := refHash(t, origin, plumbing.NewBranchReferenceName("master"))
+	release := refHash(t, origin, plumbing.NewBranchReferenceName("release"))
+
+	syncer := reposync.New(&fakeProvider{repos: []reposync.Repository{
+		{Path: "api", FullName: "org/api", CloneURL: originPath, DefaultBranch: "master"},
+	}}, reposync.Options{BaseDir: base, Before: beforeSync, After: finishSync})
+	clonePath := filepath.Join(base, "api")
+
+	run := func(step, wantRef string) *git.Repository {
+		t.Helper()
+		results, err := syncer.Run(context.Background())
+		if err != nil || len(results) != 1 || results[0].Err != nil {
+			t.Fatalf("%s: Run() = %+v, %v", step, results, err)
+		}
+		if got := results[0].Fields["ref"]; got != wantRef {
+			t.Errorf("%s: reported ref %v, want %s", step, got, wantRef)
+		}
+		r, err := git.PlainOpen(clonePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	checkHead := func(step string, r *git.Repository, branch string, present, absent string) {
+		t.Helper()
+		head, err := r.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.Target() != plumbing.NewBranchReferenceName(branch) {
+			t.Errorf("%s: HEAD on %s, want %s", step, head.Target(), branch)
+		}
+		if _, err := os.Stat(filepath.Join(clonePath, present)); err != nil {
+			t.Errorf("%s: %s missing from the worktree", step, present)
+		}
+		if _, err := os.Stat(filepath.Join(clonePath, absent)); err == nil {
+			t.Errorf("%s: %s left in the worktree", step, absent)
+		}
+	}
+
+	setFlag(t, "ref", "release")
+	r := run("with -ref", "release")
+	checkHead("with -ref", r, "release", "release.txt", "main.go")
+	if got := refHash(t, r, plumbing.NewBranchReferenceName("release")); got != release {
+		t.Errorf("with -ref: release at %s, want %s", got, release)
+	}
+
+	setFlag(t, "ref", "")
+	r = run("without -ref", "master")
+	checkHead("without -ref", r, "master", "main.go", "release.txt")
+	if got := refHash(t, r, plumbing.NewBranchReferenceName("master")); got != master {
+		t.Errorf("without -ref: master at %s, want %s", got, master)
+	}
+	if got := refHash(t, r, plumbing.NewBranchReferenceName("release")); got != release {
+		t.Errorf("without -ref: release moved to %s, want it left at %s", got, release)
+	}
+}
+
+func TestCheckoutRefTag(t *testing.T) {
+	origins, base := t.TempDir(), t.TempDir()
+	origin, originPath := newOrigin(t, origins, "api")
+	tagged := commitFile(t, origin, originPath, "main.go", "package main")
+	if _, err := origin.CreateTag("v1.0.0", tagged, nil); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, origin, originPath, "go.mod", "module api")
+
+	clonePath := filepath.Join(base, "api")
+	if _, err := git.PlainClone(clonePath, false, &git.CloneOptions{URL: originPath, Tags: git.NoTags}); err != nil {
+		t.Fatal(err)
+	}
+	task := reposync.Task{Repository: reposync.Repository{CloneURL: originPath}, Dir: clonePath, Branch: "master", Timeout: time.Minute}
+	tag := plumbing.NewTagReferenceName("v1.0.0")
+
+	// The tag is fetched on demand, which stops with the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := checkoutRefWithTimeout(ctx, task, tag); !errors.Is(err, context.Canceled) {
+		t.Errorf("checkoutRefWithTimeout() error = %v with a cancelled context, want context.Canceled", err)
+	}
+
+	if err := checkoutRefWithTimeout(context.Background(), task, tag); err != nil {
+		t.Fatalf("checkoutRefWithTimeout() error = %v", err)
+	}
+	r, err := git.PlainOpen(clonePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head := refHash(t, r, plumbing.HEAD); head != tagged {
+		t.Errorf("HEAD at %s, want the tagged commit %s", head, tagged)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "go.mod")); err == nil {
+		t.Error("go.mod, committed after the tag, left in the worktree")
+	}
+}
@@ -0,0 +1,50 @@
+package cloner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, spec := range []string{"@every 30m", "@hourly", "@daily", "@weekly", "*/15 9-17 * * 1-5", "0 0 1,15 * 7"} {
+		if _, err := parseSchedule(spec); err != nil {
+			t.Errorf("parseSchedule(%q) error = %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "@every -1h", "@every soon", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Monday 2024-01-01 10:07.
+	from := time.Date(2024, time.January, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@every 90m", from.Add(90 * time.Minute)},
+		{"@hourly", time.Date(2024, time.January, 1, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 1, 10, 15, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2024, time.January, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.January, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 15th or any Friday, whichever comes first.
+		{"0 0 15 * 5", time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 3 * 5", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		// Only one restricted: both must match.
+		{"0 0 */2 * *", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		sched, err := parseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q) error = %v", tt.spec, err)
+		}
+		if got := sched.next(from); !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
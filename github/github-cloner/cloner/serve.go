@@ -0,0 +1,243 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/google/go-github/v42/github"
+	"github.com/pterm/pterm"
+)
+
+var listenFlag = flags.String("listen", ":8080", "address the serve mode listens on")
+var scheduleFlag = flags.String("schedule", "@every 1h", "full sync schedule of the serve mode, @every <duration> or a cron expression")
+
+// repoStatus is the outcome of the last sync of a repository, shown on the status page.
+type repoStatus struct {
+	Name     string
+	Result   string
+	Ref      string
+	LastSync time.Time
+	Duration string
+	Error    string
+}
+
+// syncStatus keeps the known repositories and their last sync while serving. It stays nil in the other modes.
+type syncStatus struct {
+	mu           sync.Mutex
+	tasks        map[string]reposync.Task
+	repos        map[string]*repoStatus
+	lastFullSync time.Time
+}
+
+var status *syncStatus
+
+// registerTask remembers how to sync a repository, so a webhook can trigger it later.
+func registerTask(task reposync.Task) {
+	if status == nil {
+		return
+	}
+	status.mu.Lock()
+	defer status.mu.Unlock()
+
+	status.tasks[task.FullName] = task
+	if _, ok := status.repos[task.FullName]; !ok {
+		status.repos[task.FullName] = &repoStatus{Name: task.FullName, Result: eventQueued}
+	}
+}
+
+// recordResult stores the outcome of a repository sync for the status page.
+func recordResult(res reposync.Result) {
+	if status == nil {
+		return
+	}
+	status.mu.Lock()
+	defer status.mu.Unlock()
+
+	ref, _ := res.Fields["ref"].(string)
+	s := &repoStatus{Name: res.Task.FullName, Result: string(res.Status), Ref: ref, LastSync: time.Now(), Duration: roundDuration(res.Duration)}
+	switch {
+	case res.Err != nil:
+		s.Error = res.Err.Error()
+	case res.Status == reposync.StatusSkipped || res.Status == statusOversized:
+		s.Error = res.Reason
+	}
+	status.repos[res.Task.FullName] = s
+}
+
+// runServe keeps baseDir in sync on a schedule, and updates single repositories when GitHub sends a push webhook.
+func runServe(ctx context.Context, syncer *reposync.Syncer, target, baseDir string) error {
+	sched, err := parseSchedule(*scheduleFlag)
+	if err != nil {
+		return err
+	}
+
+	status = &syncStatus{tasks: map[string]reposync.Task{}, repos: map[string]*repoStatus{}}
+
+	// saveMu serialises the writes of the state file and workspaces. Syncs themselves don't need it, as the
+	// syncer serialises the ones of the same repository, so webhook updates don't wait for the full sync.
+	var saveMu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			// Listing errors are already reported by the event reporter, and the next run retries.
+			_, _ = syncer.Run(ctx)
+
+			saveMu.Lock()
+			saveServeState()
+			if *workspaceFlag {
+				if err := generateWorkspaces(baseDir, target); err != nil {
+					logger.Error("failed to generate workspaces", logger.Args("error", err.Error()))
+				}
+			}
+			saveMu.Unlock()
+			status.mu.Lock()
+			status.lastFullSync = time.Now()
+			status.mu.Unlock()
+
+			next := sched.next(time.Now())
+			logger.Info("next full sync", logger.Args("at", next))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/", handleStatus)
+
+	// Without a secret anybody could trigger syncs, so the webhook is only served when one is configured.
+	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if secret != "" {
+		mux.HandleFunc("/webhook", webhookHandler(ctx, syncer, &saveMu, []byte(secret)))
+	} else {
+		logger.Warn("GITHUB_WEBHOOK_SECRET not set, the /webhook endpoint is disabled")
+	}
+
+	if isInteractive() {
+		pterm.Info.Printf("Serving %s on %s, full sync schedule %s\n", target, *listenFlag, *scheduleFlag)
+	}
+
+	// Once ctx is cancelled, stop accepting requests and wait for the sync in progress to save its state.
+	server := &http.Server{Addr: *listenFlag, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// saveServeState persists the sync state while serving, logging failures since serving goes on regardless.
+func saveServeState() {
+	if err := saveSyncState(); err != nil {
+		logger.Error("failed to save sync state", logger.Args("error", err.Error()))
+	}
+}
+
+// webhookHandler verifies GitHub push payloads and updates the pushed repository right away, even while a full
+// sync is in progress. The state is then saved holding saveMu.
+func webhookHandler(ctx context.Context, syncer *reposync.Syncer, saveMu *sync.Mutex, secret []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := github.ValidatePayload(r, secret)
+		if err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		switch github.WebHookType(r) {
+		case "ping":
+			w.WriteHeader(http.StatusOK)
+			return
+		case "push":
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		event, err := github.ParseWebHook(github.WebHookType(r), payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		push, ok := event.(*github.PushEvent)
+		if !ok {
+			http.Error(w, "unexpected payload", http.StatusBadRequest)
+			return
+		}
+
+		name := push.GetRepo().GetFullName()
+		status.mu.Lock()
+		task, ok := status.tasks[name]
+		status.mu.Unlock()
+		if !ok {
+			http.Error(w, "unknown repository "+name, http.StatusNotFound)
+			return
+		}
+
+		logEvent(eventQueued, task.Path, "trigger", "webhook", "ref", push.GetRef())
+		go func() {
+			syncer.Sync(ctx, task)
+			saveMu.Lock()
+			defer saveMu.Unlock()
+			saveServeState()
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head><title>github-cloner</title></head>
+<body>
+<h1>github-cloner</h1>
+<p>Last full sync: {{if .LastFullSync.IsZero}}in progress{{else}}{{.LastFullSync.Format "2006-01-02 15:04:05"}}{{end}}</p>
+<table border="1" cellpadding="4">
+<tr><th>Repository</th><th>Result</th><th>Ref</th><th>Last sync</th><th>Duration</th><th>Error</th></tr>
+{{range .Repos}}<tr><td>{{.Name}}</td><td>{{.Result}}</td><td>{{.Ref}}</td><td>{{if not .LastSync.IsZero}}{{.LastSync.Format "2006-01-02 15:04:05"}}{{end}}</td><td>{{.Duration}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	status.mu.Lock()
+	repos := make([]repoStatus, 0, len(status.repos))
+	for _, s := range status.repos {
+		repos = append(repos, *s)
+	}
+	lastFullSync := status.lastFullSync
+	status.mu.Unlock()
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = statusTemplate.Execute(w, struct {
+		LastFullSync time.Time
+		Repos        []repoStatus
+	}{lastFullSync, repos})
+}
@@ -0,0 +1,118 @@
+package cloner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+)
+
+const (
+	orderListed   = "listed"
+	orderLargest  = "largest"
+	orderSmallest = "smallest"
+)
+
+var maxRepoSizeFlag = flags.String("max-repo-size", "", "skip repositories larger than this size, e.g. 500MB")
+var diskBudgetFlag = flags.String("disk-budget", "", "stop queueing repositories once their total size exceeds this budget, e.g. 20GB")
+var orderFlag = flags.String("order", orderListed, "order in which repositories are synced: listed, largest or smallest")
+var timeoutPerGBFlag = flags.String("timeout-per-gb", "2m", "extra timeout granted per GB of reported repository size")
+
+// sizeLimits holds the parsed size flags. Zero values disable the corresponding limit.
+type sizeLimits struct {
+	maxRepoKB    int64
+	diskBudgetKB int64
+	usedKB       int64
+	timeoutPerGB time.Duration
+}
+
+func parseSizeLimits() (*sizeLimits, error) {
+	var l sizeLimits
+	var err error
+	if l.maxRepoKB, err = parseSizeKB(*maxRepoSizeFlag); err != nil {
+		return nil, fmt.Errorf("invalid -max-repo-size: %w", err)
+	}
+	if l.diskBudgetKB, err = parseSizeKB(*diskBudgetFlag); err != nil {
+		return nil, fmt.Errorf("invalid -disk-budget: %w", err)
+	}
+	if l.timeoutPerGB, err = time.ParseDuration(*timeoutPerGBFlag); err != nil {
+		return nil, fmt.Errorf("invalid -timeout-per-gb: %w", err)
+	}
+	switch *orderFlag {
+	case orderListed, orderLargest, orderSmallest:
+	default:
+		return nil, fmt.Errorf("invalid -order %q, expected listed, largest or smallest", *orderFlag)
+	}
+	return &l, nil
+}
+
+// parseSizeKB parses sizes like 512KB, 500MB or 2GB into kilobytes, the unit GitHub reports repository sizes in.
+// A bare number is taken as megabytes.
+func parseSizeKB(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		kb     float64
+	}{{"TB", 1 << 30}, {"GB", 1 << 20}, {"MB", 1 << 10}, {"KB", 1}}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := float64(1 << 10)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			multiplier = u.kb
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// orderRepos sorts the repositories by their reported size as requested by -order.
+func orderRepos(repos []*github.Repository) {
+	switch *orderFlag {
+	case orderLargest:
+		sort.SliceStable(repos, func(i, j int) bool { return repos[i].GetSize() > repos[j].GetSize() })
+	case orderSmallest:
+		sort.SliceStable(repos, func(i, j int) bool { return repos[i].GetSize() < repos[j].GetSize() })
+	}
+}
+
+// admit reports why a repository of sizeKB can't be synced, or an empty string if it fits the limits. Admitted
+// repositories are charged against the disk budget.
+func (l *sizeLimits) admit(sizeKB int64) string {
+	if l.maxRepoKB > 0 && sizeKB > l.maxRepoKB {
+		return fmt.Sprintf("size %s exceeds -max-repo-size %s", formatKB(sizeKB), formatKB(l.maxRepoKB))
+	}
+	if l.diskBudgetKB > 0 && l.usedKB+sizeKB > l.diskBudgetKB {
+		return fmt.Sprintf("size %s exceeds the remaining -disk-budget %s", formatKB(sizeKB), formatKB(l.diskBudgetKB-l.usedKB))
+	}
+	l.usedKB += sizeKB
+	return ""
+}
+
+// timeout scales the base timeout by the reported size of the repository.
+func (l *sizeLimits) timeout(base time.Duration, sizeKB int64) time.Duration {
+	return base + time.Duration(float64(l.timeoutPerGB)*float64(sizeKB)/(1<<20))
+}
+
+func formatKB(kb int64) string {
+	switch {
+	case kb >= 1<<20:
+		return fmt.Sprintf("%.1fGB", float64(kb)/(1<<20))
+	case kb >= 1<<10:
+		return fmt.Sprintf("%.1fMB", float64(kb)/(1<<10))
+	default:
+		return fmt.Sprintf("%dKB", kb)
+	}
+}
@@ -0,0 +1,44 @@
+package cloner
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+var sparseFlag = flags.String("sparse", "", "comma separated path patterns to materialise in every worktree, e.g. infra,modules/*-api,*.md")
+var sparseConfigFlag = flags.String("sparse-config", "", "JSON file mapping repository names to the path patterns to materialise")
+var fullCheckoutFlag = flags.Bool("full-checkout", false, "check out the whole tree again in clones synced with -sparse or -sparse-config")
+
+// sparseConfig maps a repository name or full name to its sparse patterns, overriding -sparse. Patterns are
+// matched against every file and its parent directories, see reposync.MatchSparse.
+var sparseConfig map[string][]string
+
+func loadSparseConfig(path string) error {
+	if *fullCheckoutFlag && (path != "" || *sparseFlag != "") {
+		return errors.New("-full-checkout can't be combined with -sparse or -sparse-config")
+	}
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &sparseConfig)
+}
+
+// sparseFor returns the patterns configured for a repository, or nil to keep the ones saved by a previous sync.
+func sparseFor(name, fullName string) []string {
+	if dirs, ok := sparseConfig[fullName]; ok {
+		return dirs
+	}
+	if dirs, ok := sparseConfig[name]; ok {
+		return dirs
+	}
+	if *sparseFlag == "" {
+		return nil
+	}
+	return strings.Split(*sparseFlag, ",")
+}
@@ -0,0 +1,203 @@
+package cloner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStateFile = ".github-cloner-state.json"
+	// defaultCacheFile is kept next to the state file.
+	defaultCacheFile = ".github-cloner-cache.json"
+)
+
+var stateFlag = flags.String("state", "", "path of the sync state file (defaults to <path>/"+defaultStateFile+")")
+var forceFlag = flags.Bool("force", false, "fetch every repository, even if it was not pushed since the last sync")
+
+// repoState records the last successful sync of a repository, with the settings it was synced with. A repository
+// whose -ref, sparse directories or upstream changed since is synced again, even if it was not pushed.
+type repoState struct {
+	PushedAt time.Time `json:"pushed_at"`
+	SyncedAt time.Time `json:"synced_at"`
+	Ref      string    `json:"ref,omitempty"`
+	Sparse   []string  `json:"sparse,omitempty"`
+	Upstream string    `json:"upstream,omitempty"`
+}
+
+// syncState is persisted between runs, so repositories that were not pushed since the last run can be skipped.
+type syncState struct {
+	mu    sync.Mutex
+	path  string
+	Repos map[string]repoState `json:"repos"`
+}
+
+// loadState reads the state file at path. A missing file yields an empty state.
+func loadState(path string) (*syncState, error) {
+	st := &syncState{path: path}
+	if err := loadJSON(path, st); err != nil {
+		return nil, err
+	}
+	if st.Repos == nil {
+		st.Repos = map[string]repoState{}
+	}
+	return st, nil
+}
+
+func (s *syncState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return saveJSON(s.path, s)
+}
+
+// unchanged reports if the repository was synced successfully after its last push, with the same settings as cur.
+func (s *syncState) unchanged(repo string, cur repoState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, ok := s.Repos[repo]
+	return ok && !cur.PushedAt.IsZero() && !cur.PushedAt.After(prev.PushedAt) &&
+		cur.Ref == prev.Ref && slices.Equal(cur.Sparse, prev.Sparse) && cur.Upstream == prev.Upstream
+}
+
+func (s *syncState) markSynced(repo string, cur repoState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur.SyncedAt = time.Now().UTC()
+	s.Repos[repo] = cur
+}
+
+// cachedPage is a GitHub API response kept to answer conditional requests that return 304 Not Modified.
+type cachedPage struct {
+	ETag string `json:"etag"`
+	Link string `json:"link,omitempty"`
+	Body string `json:"body"`
+}
+
+// pageCache holds the API responses replayed by etagTransport. They are bulky, so they are kept apart from the
+// sync state in their own file.
+type pageCache struct {
+	mu    sync.Mutex
+	path  string
+	Pages map[string]cachedPage `json:"pages"`
+}
+
+// loadPageCache reads the cache file at path. A missing file yields an empty cache.
+func loadPageCache(path string) (*pageCache, error) {
+	c := &pageCache{path: path}
+	if err := loadJSON(path, c); err != nil {
+		return nil, err
+	}
+	if c.Pages == nil {
+		c.Pages = map[string]cachedPage{}
+	}
+	return c, nil
+}
+
+func (c *pageCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return saveJSON(c.path, c)
+}
+
+func (c *pageCache) page(url string) (cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.Pages[url]
+	return p, ok
+}
+
+func (c *pageCache) store(url string, p cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Pages[url] = p
+}
+
+// loadJSON decodes the file at path into v, leaving v untouched if the file doesn't exist.
+func loadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// saveJSON writes v to the file at path, creating its directory if needed.
+func saveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// etagTransport sends If-None-Match for GET requests whose response was cached on a previous run. A 304 answer
+// is turned into the cached 200 response, so go-github paginates as usual without spending rate limit.
+type etagTransport struct {
+	base  http.RoundTripper
+	cache *pageCache
+	force bool
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached, ok := t.cache.page(key)
+	if ok && !t.force {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		_ = resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK))
+		resp.Body = io.NopCloser(bytes.NewBufferString(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		if cached.Link != "" {
+			resp.Header.Set("Link", cached.Link)
+		}
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.cache.store(key, cachedPage{
+			ETag: resp.Header.Get("ETag"),
+			Link: resp.Header.Get("Link"),
+			Body: string(body),
+		})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return resp, nil
+}
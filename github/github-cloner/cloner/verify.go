@@ -0,0 +1,99 @@
+package cloner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const (
+	signatureVerified   = "verified"
+	signatureUnsigned   = "unsigned"
+	signatureUnknownKey = "unknown-key"
+)
+
+var verifyFlag = flags.Bool("verify-signatures", false, "verify the signature of the HEAD commit after every clone or update")
+var keyringFlag = flags.String("keyring", "", "armored GPG keyring with the keys allowed to sign HEAD commits")
+var allowedSignersFlag = flags.String("allowed-signers", "", "SSH allowed signers file with the keys allowed to sign HEAD commits")
+
+// keyring is the content of -keyring, loaded once at startup.
+var keyring string
+
+func loadSigners() error {
+	if !*verifyFlag {
+		return nil
+	}
+	if *keyringFlag == "" && *allowedSignersFlag == "" {
+		return fmt.Errorf("-verify-signatures requires -keyring, -allowed-signers or both")
+	}
+	if *keyringFlag != "" {
+		data, err := os.ReadFile(*keyringFlag)
+		if err != nil {
+			return err
+		}
+		keyring = string(data)
+	}
+	if *allowedSignersFlag != "" {
+		// git resolves the allowed signers file relative to the repository, so make it absolute.
+		abs, err := filepath.Abs(*allowedSignersFlag)
+		if err != nil {
+			return err
+		}
+		*allowedSignersFlag = abs
+	}
+	return nil
+}
+
+// verifyHead checks the signature of the commit checked out in path. GPG signatures are verified with go-git,
+// SSH signatures with git itself since go-git does not support them.
+func verifyHead(path string) (string, error) {
+	r, err := git.PlainOpen(path)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case commit.PGPSignature == "":
+		return signatureUnsigned, nil
+	case strings.HasPrefix(commit.PGPSignature, "-----BEGIN SSH SIGNATURE-----"):
+		if *allowedSignersFlag == "" {
+			return signatureUnknownKey, nil
+		}
+		cmd := exec.Command("git", "-C", path,
+			"-c", "gpg.ssh.allowedSignersFile="+*allowedSignersFlag,
+			"verify-commit", head.Hash().String())
+		out, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			// git ran but rejected the signature.
+			logger.Debug("ssh signature not verified", logger.Args("path", path, "output", strings.TrimSpace(string(out))))
+			return signatureUnknownKey, nil
+		case err != nil:
+			return "", fmt.Errorf("failed to run git verify-commit: %w", err)
+		}
+		return signatureVerified, nil
+	default:
+		if keyring == "" {
+			return signatureUnknownKey, nil
+		}
+		if _, err := commit.Verify(keyring); err != nil {
+			logger.Debug("gpg signature not verified", logger.Args("path", path, "error", err.Error()))
+			return signatureUnknownKey, nil
+		}
+		return signatureVerified, nil
+	}
+}
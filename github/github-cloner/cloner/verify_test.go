@@ -0,0 +1,67 @@
+package cloner
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newSSHSignedOrigin creates a repository whose HEAD commit carries an SSH signature no key can verify.
+func newSSHSignedOrigin(t *testing.T) string {
+	t.Helper()
+
+	r, path := newOrigin(t, t.TempDir(), "api")
+	parent, err := r.CommitObject(refHash(t, r, plumbing.HEAD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	commit := &object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      "signed",
+		TreeHash:     parent.TreeHash,
+		ParentHashes: []plumbing.Hash{parent.Hash},
+		PGPSignature: "-----BEGIN SSH SIGNATURE-----\nU1NIU0lHAAAAAQ==\n-----END SSH SIGNATURE-----\n",
+	}
+	obj := r.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), hash)); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyHeadSSHSignature(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	setupTestState(t)
+	path := newSSHSignedOrigin(t)
+
+	previous := *allowedSignersFlag
+	*allowedSignersFlag = "/dev/null"
+	t.Cleanup(func() { *allowedSignersFlag = previous })
+
+	signature, err := verifyHead(path)
+	if err != nil || signature != signatureUnknownKey {
+		t.Errorf("verifyHead() = %q, %v, want %q for a signature git rejects", signature, err, signatureUnknownKey)
+	}
+
+	// Failing to run git at all is an error, not an unverified signature.
+	t.Setenv("PATH", t.TempDir())
+	signature, err = verifyHead(path)
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("verifyHead() = %q, %v without git, want exec.ErrNotFound", signature, err)
+	}
+}
@@ -0,0 +1,131 @@
+package cloner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+)
+
+var workspaceFlag = flags.Bool("workspace", false, "generate a VS Code workspace and a go.work for the synced repositories")
+
+// generateWorkspaces writes <target>.code-workspace and go.work under baseDir, for every clone found there. Clones
+// are looked up on disk rather than taken from the sync results, so repositories that failed or were skipped in
+// this run are kept, and both files are only rewritten when their content changes.
+func generateWorkspaces(baseDir, target string) error {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return err
+	}
+	var repos []string
+	for _, entry := range entries {
+		if entry.IsDir() && reposync.IsGitRepo(filepath.Join(baseDir, entry.Name())) {
+			repos = append(repos, entry.Name())
+		}
+	}
+	sort.Strings(repos)
+
+	if err := writeCodeWorkspace(filepath.Join(baseDir, target+".code-workspace"), repos); err != nil {
+		return err
+	}
+	return writeGoWork(baseDir, repos)
+}
+
+// writeCodeWorkspace replaces the folders of a multi-root workspace, keeping any other setting already there.
+func writeCodeWorkspace(path string, repos []string) error {
+	workspace := map[string]any{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &workspace); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	folders := make([]map[string]string, 0, len(repos))
+	for _, repo := range repos {
+		folders = append(folders, map[string]string{"path": repo})
+	}
+	workspace["folders"] = folders
+	if _, ok := workspace["settings"]; !ok {
+		workspace["settings"] = map[string]any{}
+	}
+
+	out, err := json.MarshalIndent(workspace, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeIfChanged(path, append(out, '\n'))
+}
+
+// writeGoWork uses every repository with a go.mod at its root, at the highest go version they declare. A go.work
+// left by a previous run is removed once none of them has a go.mod.
+func writeGoWork(baseDir string, repos []string) error {
+	var modules []string
+	version := ""
+	for _, repo := range repos {
+		v, err := goModVersion(filepath.Join(baseDir, repo, "go.mod"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		modules = append(modules, repo)
+		if compareVersions(v, version) > 0 {
+			version = v
+		}
+	}
+	path := filepath.Join(baseDir, "go.work")
+	if len(modules) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	var b strings.Builder
+	if version != "" {
+		b.WriteString("go " + version + "\n\n")
+	}
+	b.WriteString("use (\n")
+	for _, module := range modules {
+		b.WriteString("\t./" + module + "\n")
+	}
+	b.WriteString(")\n")
+	return writeIfChanged(path, []byte(b.String()))
+}
+
+// goModVersion returns the go directive of a go.mod file, or an empty string if it has none.
+func goModVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return fields[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+func writeIfChanged(path string, content []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	return os.WriteFile(path, content, 0644)
+}
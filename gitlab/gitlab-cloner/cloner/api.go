@@ -0,0 +1,116 @@
+package cloner
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/xanzy/go-gitlab"
+)
+
+var retriesFlag = flags.Int("retries", 3, "retries of GitLab API requests and git operations failing with a transient error")
+
+// maxRateLimitWait caps how long a request waits for the rate limit to be reset, in case of clock skew.
+const maxRateLimitWait = 2 * time.Minute
+
+// apiRetries counts the GitLab API requests retried by every client. Most are made while listing, so they're
+// reported as a total rather than per project.
+var apiRetries atomic.Int64
+
+// apiOptions configures a GitLab client sending its requests with httpClient to honour the rate limits of the
+// instance, and to retry rate limited, failed and server error requests with a backoff.
+func apiOptions(httpClient *http.Client) []gitlab.ClientOptionFunc {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &rateLimitTransport{base: base}
+
+	return []gitlab.ClientOptionFunc{
+		gitlab.WithHTTPClient(httpClient),
+		gitlab.WithCustomRetryMax(*retriesFlag),
+		gitlab.WithCustomRetryWaitMinMax(time.Second, 30*time.Second),
+		gitlab.WithCustomRetry(retryablehttp.DefaultRetryPolicy),
+		gitlab.WithCustomBackoff(apiBackoff),
+	}
+}
+
+// apiBackoff waits as long as GitLab asks to with Retry-After or, once rate limited, RateLimit-Reset, and backs
+// off exponentially otherwise. It's only called before an actual retry, so it counts them too.
+func apiBackoff(min, max time.Duration, attempt int, resp *http.Response) time.Duration {
+	apiRetries.Add(1)
+
+	if resp != nil {
+		if wait, ok := retryAfter(resp); ok {
+			return wait
+		}
+	}
+	return retryablehttp.DefaultBackoff(min, max, attempt, resp)
+}
+
+// retryAfter returns the wait requested by a response, from its Retry-After header in seconds or as a date, or
+// from RateLimit-Reset for rate limited responses.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return min(time.Duration(seconds)*time.Second, maxRateLimitWait), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return min(max(time.Until(date), 0), maxRateLimitWait), true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if reset, ok := rateLimitReset(resp.Header); ok {
+			return min(max(time.Until(reset), 0), maxRateLimitWait), true
+		}
+	}
+	return 0, false
+}
+
+func rateLimitReset(header http.Header) (time.Time, bool) {
+	reset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0), true
+}
+
+// rateLimitTransport holds requests back once GitLab reports with RateLimit-Remaining that the rate limit is
+// exhausted, until RateLimit-Reset. It's shared by the listing and the workers.
+type rateLimitTransport struct {
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	resumeAt time.Time
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	wait := min(time.Until(t.resumeAt), maxRateLimitWait)
+	t.mu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining")); err == nil && remaining <= 0 {
+		if reset, ok := rateLimitReset(resp.Header); ok {
+			t.mu.Lock()
+			t.resumeAt = reset
+			t.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+package cloner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Both default to an environment variable, read when Run starts so that dxutils can provide it from its config.
+var baseURLFlag = flags.String("base-url", "", "URL of a self-managed GitLab instance, defaults to GITLAB_URL or gitlab.com")
+var caBundleFlag = flags.String("ca-bundle", "", "PEM file with extra CA certificates trusted for the instance, defaults to GITLAB_CA_BUNDLE")
+
+// newClient creates a GitLab API client for gitlab.com or the configured self-managed instance. The returned CA
+// bundle must be trusted by the git operations too.
+func newClient(token string) (*gitlab.Client, []byte, error) {
+	var opts []gitlab.ClientOptionFunc
+
+	baseURL := *baseURLFlag
+	if baseURL == "" {
+		baseURL = os.Getenv("GITLAB_URL")
+	}
+	if baseURL != "" {
+		// The client appends the /api/v4 path itself when it's missing.
+		opts = append(opts, gitlab.WithBaseURL(baseURL))
+	}
+
+	caPath := *caBundleFlag
+	if caPath == "" {
+		caPath = os.Getenv("GITLAB_CA_BUNDLE")
+	}
+	caBundle, httpClient, err := loadCABundle(caPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, apiOptions(httpClient)...)
+
+	client, err := gitlab.NewClient(token, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, caBundle, nil
+}
+
+// loadCABundle reads the PEM file at path, and returns it along with an HTTP client trusting it. An empty path
+// yields no bundle and a client trusting the system pool only.
+func loadCABundle(path string) ([]byte, *http.Client, error) {
+	if path == "" {
+		return nil, &http.Client{}, nil
+	}
+	caBundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	httpClient, err := httpClientWithCA(caBundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CA bundle %s: %w", path, err)
+	}
+	return caBundle, httpClient, nil
+}
+
+// httpClientWithCA returns an HTTP client trusting the system pool and the certificates of caBundle.
+func httpClientWithCA(caBundle []byte) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
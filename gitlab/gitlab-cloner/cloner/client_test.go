@@ -0,0 +1,123 @@
+package cloner
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setFlag sets a command line flag for the duration of the test.
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+
+	previous := flags.Lookup(name).Value.String()
+	if err := flags.Set(name, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = flags.Set(name, previous) })
+}
+
+// newTLSInstance starts a GitLab stand-in serving project 1 over HTTPS with a self-signed certificate, and returns
+// it along with the path of a PEM bundle holding that certificate.
+func newTLSInstance(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"path_with_namespace":"platform/api"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return server, path
+}
+
+func TestNewClientCABundle(t *testing.T) {
+	server, caPath := newTLSInstance(t)
+	setFlag(t, "base-url", server.URL)
+	setFlag(t, "retries", "0")
+	t.Setenv("GITLAB_CA_BUNDLE", "")
+
+	client, caBundle, err := newClient("token")
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if caBundle != nil {
+		t.Errorf("newClient() returned a CA bundle without -ca-bundle")
+	}
+	if _, _, err := client.Projects.GetProject(1, nil); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("GetProject() error = %v, want a certificate error without the CA bundle", err)
+	}
+
+	// The bundle is read from GITLAB_CA_BUNDLE when -ca-bundle isn't set.
+	t.Setenv("GITLAB_CA_BUNDLE", caPath)
+	client, caBundle, err = newClient("token")
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if len(caBundle) == 0 {
+		t.Errorf("newClient() returned no CA bundle, git operations wouldn't trust the instance")
+	}
+	project, _, err := client.Projects.GetProject(1, nil)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v with the CA bundle", err)
+	}
+	if project.PathWithNamespace != "platform/api" {
+		t.Errorf("GetProject() = %s, want platform/api", project.PathWithNamespace)
+	}
+}
+
+func TestNewClientInvalidCABundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, "ca-bundle", path)
+
+	if _, _, err := newClient("token"); err == nil || !strings.Contains(err.Error(), "no PEM certificate found") {
+		t.Errorf("newClient() error = %v, want an invalid CA bundle error", err)
+	}
+}
+
+func TestNewMirrorCABundle(t *testing.T) {
+	server, caPath := newTLSInstance(t)
+	_, sourceCAPath := newTLSInstance(t)
+	setFlag(t, "mirror-url", server.URL)
+	setFlag(t, "mirror-namespace", "migrated")
+	setFlag(t, "retries", "0")
+	setFlag(t, "ca-bundle", sourceCAPath)
+	t.Setenv("MIRROR_TOKEN", "token")
+	t.Setenv("MIRROR_CA_BUNDLE", "")
+
+	getProject := func() error {
+		t.Helper()
+		m, err := newMirror()
+		if err != nil {
+			t.Fatalf("newMirror() error = %v", err)
+		}
+		_, _, err = m.target.(*gitLabTarget).client.Projects.GetProject(1, nil)
+		return err
+	}
+
+	// The CA bundle of the source instance is not trusted for the mirror target.
+	if err := getProject(); err == nil {
+		t.Errorf("GetProject() succeeded on the mirror target with the source CA bundle only")
+	}
+
+	setFlag(t, "mirror-ca-bundle", caPath)
+	if err := getProject(); err != nil {
+		t.Errorf("GetProject() error = %v with -mirror-ca-bundle", err)
+	}
+}
@@ -0,0 +1,254 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/pterm/pterm"
+	"github.com/xanzy/go-gitlab"
+)
+
+// flags holds every option of gitlab-cloner. The standalone binary parses it from the command line, and
+// dxutils exposes it on its "clone gitlab" command.
+var flags = flag.NewFlagSet("gitlab-cloner", flag.ExitOnError)
+
+var timeoutFlag = flags.String("timeout", "2m", "timeout duration for git operations")
+
+// defaultBranch is used for empty projects and when the default branch of a remote can't be discovered.
+var defaultBranch = "main"
+
+// Directory to clone repositories
+var baseDir = flags.String("path", "./", "Path to clone repositories")
+
+// GitLab group/subgroup
+var groupPath = flags.String("group", "", "GitLab group or subgroup to clone from")
+
+var workersFlag = flags.Int("workers", 4, "number of projects cloned or updated concurrently")
+
+var queueSizeFlag = flags.Int("queue-size", 1000, "number of listed projects buffered ahead of the workers")
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run clones or updates every project of the selected source, and pushes it to the mirror target in mirror mode.
+func Run(ctx context.Context) error {
+	token := os.Getenv("GITLAB_PRIVATE_TOKEN")
+	if token == "" {
+		return errors.New("GITLAB_PRIVATE_TOKEN not set")
+	}
+
+	if *retriesFlag < 0 {
+		return fmt.Errorf("invalid number of retries %d, expected 0 or more", *retriesFlag)
+	}
+
+	gitLabClient, caBundle, err := newClient(token)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	lister, source, err := newLister(gitLabClient)
+	if err != nil {
+		return err
+	}
+
+	filter, err := newProjectFilter(time.Now())
+	if err != nil {
+		return err
+	}
+
+	timeout, err := time.ParseDuration(*timeoutFlag)
+	if err != nil {
+		return fmt.Errorf("invalid timeout duration: %s", *timeoutFlag)
+	}
+
+	auth, err := gitAuth(token)
+	if err != nil {
+		return err
+	}
+
+	if *workersFlag < 1 {
+		return fmt.Errorf("invalid number of workers %d, expected at least 1", *workersFlag)
+	}
+	if err := validateEmptyProjects(); err != nil {
+		return err
+	}
+	if err := validateWikis(); err != nil {
+		return err
+	}
+	if *queueSizeFlag < 1 {
+		return fmt.Errorf("invalid queue size %d, expected at least 1", *queueSizeFlag)
+	}
+
+	var mirrorer *mirror
+	switch *modeFlag {
+	case modeClone:
+	case modeMirror:
+		if mirrorer, err = newMirror(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid mode %q, expected %s or %s", *modeFlag, modeClone, modeMirror)
+	}
+
+	// Raw git progress of concurrent clones would interleave, so it's only shown with a single worker.
+	var gitProgress io.Writer
+	if *workersFlag == 1 {
+		gitProgress = os.Stdout
+	}
+
+	pterm.Info.Printf("Cloning %s from %s to %s\n", source, gitLabClient.BaseURL().Host, *baseDir)
+
+	title := "Cloning Projects"
+	var after func(context.Context, *reposync.Task, *reposync.Result) error
+	if mirrorer != nil {
+		pterm.Info.Printf("Mirroring them to %s %s\n", *mirrorToFlag, *mirrorNamespaceFlag)
+		title, after = "Mirroring Projects", mirrorer.after
+	}
+
+	provider := &gitLabProvider{client: gitLabClient, list: lister, filter: filter, auth: auth, wikis: map[string]bool{}}
+	reporter := reposync.NewProgressReporter(title)
+	syncer := reposync.New(provider, reposync.Options{
+		BaseDir:         *baseDir,
+		Workers:         *workersFlag,
+		QueueSize:       *queueSizeFlag,
+		IncludeArchived: *includeArchivedFlag,
+		Retries:         *retriesFlag,
+		Timeout:         timeout,
+		FallbackBranch:  defaultBranch,
+		CABundle:        caBundle,
+		GitProgress:     gitProgress,
+		Reporter:        reporter,
+		Prepare:         provider.prepare,
+		Before:          resolveBranch,
+		After:           after,
+	})
+
+	// Listing errors are already reported by the progress reporter.
+	results, _ := syncer.Run(ctx)
+	reporter.Stop()
+
+	if len(results) == 0 {
+		pterm.Warning.Println("No projects found")
+		return nil
+	}
+
+	operation := "clone or update"
+	if mirrorer != nil {
+		printMirrorReport(results)
+		operation = "mirror"
+	}
+	if failed := reposync.Count(results, reposync.StatusFailed); failed > 0 {
+		pterm.Warning.Printf("Failed to %s %d projects\n", operation, failed)
+	}
+	if retried := apiRetries.Load(); retried > 0 {
+		pterm.Info.Printf("Retried %d GitLab API requests in total\n", retried)
+	}
+	if skipped := reposync.Count(results, reposync.StatusSkipped); skipped > 0 {
+		pterm.Info.Printf("Skipped %d empty repositories\n", skipped)
+	}
+	if mirrorer != nil {
+		pterm.Success.Printf("Mirrored %d projects\n", reposync.Count(results, reposync.StatusUpdated))
+		return nil
+	}
+	pterm.Success.Printf("Cloned %d projects\n", reposync.Count(results, reposync.StatusUpdated))
+	return nil
+}
+
+// gitLabProvider lists the projects of the selected source: a group and all its subgroups, a user, the token's
+// memberships or stars, or a list file.
+type gitLabProvider struct {
+	client *gitlab.Client
+	list   projectLister
+	filter *projectFilter
+	auth   transport.AuthMethod
+	// wikis holds the full names of the wikis listed. It's only used by the listing goroutine, Prepare included.
+	wikis map[string]bool
+}
+
+// projectInfo carries the GitLab specific values of a task between the sync hooks.
+type projectInfo struct {
+	// wiki is set for project and group wikis, which are skipped rather than initialised when empty.
+	wiki bool
+}
+
+func taskInfo(task *reposync.Task) *projectInfo {
+	if info, ok := task.Data.(*projectInfo); ok {
+		return info
+	}
+	return &projectInfo{}
+}
+
+func (p *gitLabProvider) Name() string {
+	return "GitLab"
+}
+
+func (p *gitLabProvider) List(ctx context.Context, fn func(reposync.Repository) error) error {
+	var root string
+	err := p.list(ctx, func(project *gitlab.Project) error {
+		if !p.filter.match(project) {
+			return nil
+		}
+		if root == "" {
+			root = cloneRoot(project)
+		}
+
+		if err := fn(projectRepository(project)); err != nil {
+			return err
+		}
+		if !*wikisFlag || !project.WikiEnabled {
+			return nil
+		}
+		return p.listWiki(projectWiki(project), fn)
+	})
+	// Group wikis are cloned from the same host as the projects, so there is nothing to build their URL from
+	// when no project was listed.
+	if err != nil || !*groupWikisFlag || root == "" {
+		return err
+	}
+	return listGroupWikis(ctx, p.client, *groupPath, root, func(wiki reposync.Repository) error {
+		return p.listWiki(wiki, fn)
+	})
+}
+
+func (p *gitLabProvider) listWiki(wiki reposync.Repository, fn func(reposync.Repository) error) error {
+	p.wikis[wiki.FullName] = true
+	return fn(wiki)
+}
+
+// prepare records the GitLab specific values of a listed repository in its task, and skips empty projects when
+// asked to.
+func (p *gitLabProvider) prepare(ctx context.Context, task *reposync.Task) error {
+	task.Data = &projectInfo{wiki: p.wikis[task.FullName]}
+	return skipEmpty(ctx, task)
+}
+
+func (p *gitLabProvider) Auth(reposync.Repository) transport.AuthMethod {
+	return p.auth
+}
+
+func projectRepository(project *gitlab.Project) reposync.Repository {
+	repo := reposync.Repository{
+		Path:          project.PathWithNamespace,
+		FullName:      project.PathWithNamespace,
+		CloneURL:      cloneURL(project),
+		DefaultBranch: project.DefaultBranch,
+		Archived:      project.Archived,
+		Empty:         project.EmptyRepo,
+	}
+	if project.LastActivityAt != nil {
+		repo.PushedAt = *project.LastActivityAt
+	}
+	if project.Statistics != nil {
+		repo.SizeKB = project.Statistics.RepositorySize / 1024
+	}
+	return repo
+}
@@ -0,0 +1,59 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	emptyInit = "init"
+	emptySkip = "skip"
+)
+
+var emptyProjectsFlag = flags.String("empty-projects", emptyInit,
+	"what to do with projects without commits: init an empty clone with origin configured, or skip them")
+
+// skipEmpty leaves empty projects out of the sync when -empty-projects=skip.
+func skipEmpty(_ context.Context, task *reposync.Task) error {
+	if task.Empty && *emptyProjectsFlag == emptySkip {
+		return &reposync.Skip{Status: reposync.StatusSkipped, Reason: "empty project"}
+	}
+	return nil
+}
+
+// resolveBranch asks the remote for its default branch when GitLab didn't report one, instead of guessing it.
+// A remote without commits is handled as an empty project, or skipped if it's a wiki without pages. It's the
+// Before hook of the syncer.
+func resolveBranch(ctx context.Context, task *reposync.Task) error {
+	if task.Empty || task.DefaultBranch != "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	branch, err := reposync.RemoteHEAD(ctx, *task)
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		if taskInfo(task).wiki {
+			return &reposync.Skip{Status: reposync.StatusSkipped, Reason: "empty wiki"}
+		}
+		task.Empty = true
+		return skipEmpty(ctx, task)
+	}
+	// Otherwise the fallback branch is kept, and the clone reports what is wrong with the remote if anything.
+	if err == nil {
+		task.Branch = branch
+	}
+	return nil
+}
+
+func validateEmptyProjects() error {
+	if *emptyProjectsFlag != emptyInit && *emptyProjectsFlag != emptySkip {
+		return fmt.Errorf("invalid -empty-projects %q, expected %s or %s", *emptyProjectsFlag, emptyInit, emptySkip)
+	}
+	return nil
+}
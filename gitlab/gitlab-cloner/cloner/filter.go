@@ -0,0 +1,148 @@
+package cloner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+var includeFlag = flags.String("include", "", "only clone projects whose path matches this regular expression")
+
+var excludeFlag = flags.String("exclude", "", "skip projects whose path matches this regular expression")
+
+var topicFlag = flags.String("topic", "", "comma separated topics, only projects with at least one of them are cloned")
+
+var visibilityFlag = flags.String("visibility", "", "comma separated visibilities to clone: public, internal or private")
+
+var activeSinceFlag = flags.String("active-since", "", "only clone projects with activity within this period, e.g. 90d or 12h")
+
+var excludeForksFlag = flags.Bool("exclude-forks", false, "skip forked projects")
+
+var includeArchivedFlag = flags.Bool("include-archived", false, "clone archived projects too")
+
+var withSharedFlag = flags.Bool("with-shared", true, "also clone projects shared into the group from other groups, as GitLab does by default")
+
+// projectFilter decides which listed projects are cloned. The zero value accepts every project.
+type projectFilter struct {
+	include      *regexp.Regexp
+	exclude      *regexp.Regexp
+	topics       map[string]bool
+	visibilities map[gitlab.VisibilityValue]bool
+	activeSince  time.Time
+	excludeForks bool
+}
+
+// newProjectFilter builds the filter described by the command line options.
+func newProjectFilter(now time.Time) (*projectFilter, error) {
+	f := &projectFilter{excludeForks: *excludeForksFlag}
+
+	var err error
+	if *includeFlag != "" {
+		if f.include, err = regexp.Compile(*includeFlag); err != nil {
+			return nil, fmt.Errorf("invalid -include expression: %w", err)
+		}
+	}
+	if *excludeFlag != "" {
+		if f.exclude, err = regexp.Compile(*excludeFlag); err != nil {
+			return nil, fmt.Errorf("invalid -exclude expression: %w", err)
+		}
+	}
+
+	if topics := splitList(*topicFlag); len(topics) > 0 {
+		f.topics = map[string]bool{}
+		for _, topic := range topics {
+			f.topics[strings.ToLower(topic)] = true
+		}
+	}
+
+	if visibilities := splitList(*visibilityFlag); len(visibilities) > 0 {
+		f.visibilities = map[gitlab.VisibilityValue]bool{}
+		for _, visibility := range visibilities {
+			switch v := gitlab.VisibilityValue(strings.ToLower(visibility)); v {
+			case gitlab.PublicVisibility, gitlab.InternalVisibility, gitlab.PrivateVisibility:
+				f.visibilities[v] = true
+			default:
+				return nil, fmt.Errorf("invalid visibility %q, expected public, internal or private", visibility)
+			}
+		}
+	}
+
+	if *activeSinceFlag != "" {
+		period, err := parsePeriod(*activeSinceFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -active-since period: %w", err)
+		}
+		f.activeSince = now.Add(-period)
+	}
+
+	return f, nil
+}
+
+// match reports whether the project passes every filter.
+func (f *projectFilter) match(project *gitlab.Project) bool {
+	if f.include != nil && !f.include.MatchString(project.PathWithNamespace) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(project.PathWithNamespace) {
+		return false
+	}
+	if f.excludeForks && project.ForkedFromProject != nil {
+		return false
+	}
+	if f.visibilities != nil && !f.visibilities[project.Visibility] {
+		return false
+	}
+	if f.topics != nil && !hasAnyTopic(project, f.topics) {
+		return false
+	}
+	if !f.activeSince.IsZero() && (project.LastActivityAt == nil || project.LastActivityAt.Before(f.activeSince)) {
+		return false
+	}
+	return true
+}
+
+func hasAnyTopic(project *gitlab.Project, topics map[string]bool) bool {
+	// TagList is the name of topics before GitLab 14.0.
+	for _, list := range [][]string{project.Topics, project.TagList} {
+		for _, topic := range list {
+			if topics[strings.ToLower(topic)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parsePeriod parses a duration that may also be expressed in days or weeks, e.g. 90d or 2w.
+func parsePeriod(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(s)
+	}
+
+	count, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("%q is not a whole number of days or weeks", s)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// splitList splits a comma separated option, ignoring blank entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
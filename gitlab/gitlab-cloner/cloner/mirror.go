@@ -0,0 +1,213 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/pterm/pterm"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	modeClone  = "clone"
+	modeMirror = "mirror"
+
+	mirrorToGitLab = "gitlab"
+	mirrorToGitHub = "github"
+)
+
+var modeFlag = flags.String("mode", modeClone, "what to do with the projects: clone, or mirror them to another Git host")
+
+var mirrorToFlag = flags.String("mirror-to", mirrorToGitLab, "kind of host the mirror mode pushes to: gitlab or github")
+
+var mirrorURLFlag = flags.String("mirror-url", "", "API URL of the mirror target, defaults to gitlab.com or api.github.com")
+
+var mirrorNamespaceFlag = flags.String("mirror-namespace", "", "GitLab group or GitHub organization the projects are mirrored into")
+
+var mirrorCABundleFlag = flags.String("mirror-ca-bundle", "", "PEM file with extra CA certificates trusted for the mirror target, defaults to MIRROR_CA_BUNDLE")
+
+// mirror pushes the branches and tags of every synced project to a target host.
+type mirror struct {
+	target   mirrorTarget
+	caBundle []byte
+}
+
+// newMirror sets up the mirror target selected on the command line. Its token is read from MIRROR_TOKEN. The
+// target doesn't trust the CA bundle of the source instance, it has its own.
+func newMirror() (*mirror, error) {
+	token := os.Getenv("MIRROR_TOKEN")
+	if token == "" {
+		return nil, errors.New("MIRROR_TOKEN not set, and it's required by the mirror mode")
+	}
+	if *mirrorNamespaceFlag == "" {
+		return nil, errors.New("please specify the group or organization to mirror into with the -mirror-namespace option")
+	}
+	if *wikisFlag || *groupWikisFlag {
+		return nil, errors.New("wikis can't be mirrored, remove -wikis and -group-wikis")
+	}
+
+	caPath := *mirrorCABundleFlag
+	if caPath == "" {
+		caPath = os.Getenv("MIRROR_CA_BUNDLE")
+	}
+	caBundle, httpClient, err := loadCABundle(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("mirror target: %w", err)
+	}
+
+	namespace := strings.Trim(*mirrorNamespaceFlag, "/")
+	switch *mirrorToFlag {
+	case mirrorToGitLab:
+		opts := apiOptions(httpClient)
+		if *mirrorURLFlag != "" {
+			opts = append(opts, gitlab.WithBaseURL(*mirrorURLFlag))
+		}
+		client, err := gitlab.NewClient(token, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mirror GitLab client: %w", err)
+		}
+		return &mirror{
+			target:   &gitLabTarget{client: client, namespace: namespace, token: token, groups: map[string]int{}},
+			caBundle: caBundle,
+		}, nil
+	case mirrorToGitHub:
+		api := *mirrorURLFlag
+		if api == "" {
+			api = "https://api.github.com"
+		}
+		return &mirror{
+			target:   &gitHubTarget{api: api, org: namespace, token: token, client: httpClient},
+			caBundle: caBundle,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid -mirror-to %q, expected %s or %s", *mirrorToFlag, mirrorToGitLab, mirrorToGitHub)
+	}
+}
+
+// after ensures the mirror of a freshly synced project exists and pushes its refs there. It's the After hook of
+// the syncer in mirror mode.
+func (m *mirror) after(ctx context.Context, task *reposync.Task, res *reposync.Result) error {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	// Within a group the subgroups are recreated under the target namespace, other sources keep their full path.
+	rel := task.FullName
+	if *groupPath != "" {
+		rel = strings.TrimPrefix(rel, strings.Trim(*groupPath, "/")+"/")
+	}
+
+	url, err := m.target.ensure(ctx, rel)
+	if err != nil {
+		return err
+	}
+	res.Fields["target"] = url
+
+	var branches, tags int
+	retries, err := reposync.Retry(ctx, *retriesFlag, func() (err error) {
+		branches, tags, err = pushMirror(ctx, *task, url, m.target.auth(), m.caBundle)
+		return err
+	})
+	res.Retries += retries
+	res.Fields["branches"] = branches
+	res.Fields["tags"] = tags
+	return err
+}
+
+// pushMirror pushes every branch and tag of the task's remote from its local clone to url, and returns how many
+// of each were pushed. The remote is listed rather than the clone, so branches deleted upstream aren't pushed.
+func pushMirror(ctx context.Context, task reposync.Task, url string, auth transport.AuthMethod, caBundle []byte) (int, int, error) {
+	r, err := git.PlainOpen(task.Dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Clones and pulls only follow the tags of the fetched commits, so fetch them all.
+	err = r.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+		Auth:       task.Auth,
+		CABundle:   task.CABundle,
+		Force:      true,
+	})
+	switch {
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		return 0, 0, nil // Nothing to push, the empty mirror is enough.
+	case err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate):
+		return 0, 0, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+
+	origin, err := r.Remote("origin")
+	if err != nil {
+		return 0, 0, err
+	}
+	refs, err := origin.ListContext(ctx, &git.ListOptions{Auth: task.Auth, CABundle: task.CABundle})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list the refs of origin: %w", err)
+	}
+
+	var specs []config.RefSpec
+	branches, tags := 0, 0
+	for _, ref := range refs {
+		var local plumbing.ReferenceName
+		switch {
+		case ref.Name().IsBranch():
+			local = plumbing.NewRemoteReferenceName("origin", ref.Name().Short())
+		case ref.Name().IsTag():
+			local = ref.Name()
+		default:
+			continue
+		}
+		// Refs created upstream since the sync aren't in the clone yet, and are pushed by the next run.
+		if _, err := r.Reference(local, false); err != nil {
+			continue
+		}
+
+		specs = append(specs, config.RefSpec("+"+local.String()+":"+ref.Name().String()))
+		if ref.Name().IsBranch() {
+			branches++
+		} else {
+			tags++
+		}
+	}
+	if len(specs) == 0 {
+		return 0, 0, nil
+	}
+
+	err = r.PushContext(ctx, &git.PushOptions{
+		RemoteURL: url,
+		RefSpecs:  specs,
+		Auth:      auth,
+		CABundle:  caBundle,
+		Force:     true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return branches, tags, fmt.Errorf("failed to push to %s: %w", url, err)
+	}
+	return branches, tags, nil
+}
+
+// printMirrorReport shows the outcome of every mirrored project, with the number of refs pushed.
+func printMirrorReport(results []reposync.Result) {
+	table := pterm.TableData{{"Project", "Status", "Retries", "Branches", "Tags", "Mirror"}}
+	for _, res := range results {
+		row := []string{res.Task.FullName, string(res.Status), strconv.Itoa(res.Retries), "", "", ""}
+		if branches, ok := res.Fields["branches"].(int); ok {
+			row[3] = strconv.Itoa(branches)
+			row[4] = strconv.Itoa(res.Fields["tags"].(int))
+		}
+		if url, ok := res.Fields["target"].(string); ok {
+			row[5] = url
+		}
+		table = append(table, row)
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
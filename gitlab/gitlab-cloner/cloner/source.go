@@ -0,0 +1,216 @@
+package cloner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pterm/pterm"
+	"github.com/xanzy/go-gitlab"
+)
+
+var userFlag = flags.String("user", "", "clone the projects owned by this GitLab user instead of a group")
+
+var membershipFlag = flags.Bool("membership", false, "clone every project the token's user is a member of")
+
+var starredFlag = flags.Bool("starred", false, "clone the projects starred by the token's user")
+
+var projectsFileFlag = flags.String("projects-file", "", "file listing project paths or IDs to clone, one per line")
+
+// projectLister calls fn for every project of a source.
+type projectLister func(ctx context.Context, fn func(*gitlab.Project) error) error
+
+// newLister returns the lister of the source selected on the command line, and a description of it.
+// Exactly one source must be selected.
+func newLister(client *gitlab.Client) (projectLister, string, error) {
+	var sources []string
+	for _, source := range []struct {
+		flag string
+		set  bool
+	}{
+		{"-group", *groupPath != ""},
+		{"-user", *userFlag != ""},
+		{"-membership", *membershipFlag},
+		{"-starred", *starredFlag},
+		{"-projects-file", *projectsFileFlag != ""},
+	} {
+		if source.set {
+			sources = append(sources, source.flag)
+		}
+	}
+	switch {
+	case len(sources) == 0:
+		return nil, "", errors.New("please specify what to clone with -group, -user, -membership, -starred or -projects-file")
+	case len(sources) > 1:
+		return nil, "", fmt.Errorf("only one of -group, -user, -membership, -starred or -projects-file can be used, got %s",
+			strings.Join(sources, ", "))
+	}
+
+	switch {
+	case *userFlag != "":
+		return func(ctx context.Context, fn func(*gitlab.Project) error) error {
+			opt := &gitlab.ListProjectsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+			return paginate(ctx, fn, func(opts ...gitlab.RequestOptionFunc) ([]*gitlab.Project, *gitlab.Response, error) {
+				return client.Projects.ListUserProjects(*userFlag, opt, opts...)
+			})
+		}, "projects of user " + *userFlag, nil
+
+	case *membershipFlag, *starredFlag:
+		opt := &gitlab.ListProjectsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+		description := "projects the token's user is a member of"
+		if *membershipFlag {
+			opt.Membership = gitlab.Bool(true)
+		} else {
+			opt.Starred = gitlab.Bool(true)
+			description = "projects starred by the token's user"
+		}
+		return func(ctx context.Context, fn func(*gitlab.Project) error) error {
+			return paginate(ctx, fn, func(opts ...gitlab.RequestOptionFunc) ([]*gitlab.Project, *gitlab.Response, error) {
+				return client.Projects.ListProjects(opt, opts...)
+			})
+		}, description, nil
+
+	case *projectsFileFlag != "":
+		ids, err := readProjectsFile(*projectsFileFlag)
+		if err != nil {
+			return nil, "", err
+		}
+		return func(ctx context.Context, fn func(*gitlab.Project) error) error {
+			return listProjectIDs(ctx, client, ids, fn)
+		}, "projects listed in " + *projectsFileFlag, nil
+	}
+
+	return func(ctx context.Context, fn func(*gitlab.Project) error) error {
+		opt := &gitlab.ListGroupProjectsOptions{
+			ListOptions:      gitlab.ListOptions{PerPage: 100},
+			IncludeSubGroups: gitlab.Bool(true),
+			WithShared:       gitlab.Bool(*withSharedFlag),
+		}
+		return paginate(ctx, fn, func(opts ...gitlab.RequestOptionFunc) ([]*gitlab.Project, *gitlab.Response, error) {
+			return client.Groups.ListGroupProjects(*groupPath, opt, opts...)
+		})
+	}, "projects of group " + *groupPath, nil
+}
+
+// nextLink matches the next page in a Link header, e.g. <https://gitlab.com/api/v4/projects?id_after=42>; rel="next".
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// paginate calls list for every page of results, and fn for every item as soon as its page arrives. Keyset
+// pagination is asked for, falling back to offset pagination on the endpoints that don't support it. Pages are
+// followed through the Link header rather than counted, as GitLab leaves the totals out for large result sets.
+func paginate[T any](ctx context.Context, fn func(T) error, list func(...gitlab.RequestOptionFunc) ([]T, *gitlab.Response, error)) error {
+	items, resp, err := list(gitlab.WithContext(ctx), withKeyset)
+	if resp != nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		items, resp, err = list(gitlab.WithContext(ctx))
+	}
+
+	for {
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		query, ok := nextPage(resp)
+		if !ok {
+			return nil
+		}
+		items, resp, err = list(gitlab.WithContext(ctx), withQuery(query))
+	}
+}
+
+// withKeyset asks for keyset pagination, which GitLab supports when ordering by ID.
+func withKeyset(req *retryablehttp.Request) error {
+	query := req.URL.Query()
+	query.Set("pagination", "keyset")
+	query.Set("order_by", "id")
+	query.Set("sort", "asc")
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// withQuery replaces the query of a request with the one of a next page link, which carries every list option.
+func withQuery(query string) gitlab.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		req.URL.RawQuery = query
+		return nil
+	}
+}
+
+// nextPage returns the query of the next page from the Link header, or from X-Next-Page if a proxy dropped it.
+func nextPage(resp *gitlab.Response) (string, bool) {
+	if match := nextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+		if next, err := url.Parse(match[1]); err == nil {
+			return next.RawQuery, true
+		}
+	}
+	if resp.NextPage > 0 && resp.Request != nil {
+		query := resp.Request.URL.Query()
+		query.Set("page", strconv.Itoa(resp.NextPage))
+		return query.Encode(), true
+	}
+	return "", false
+}
+
+// readProjectsFile reads the project paths or IDs of a list file. Blank lines and lines starting with # are ignored.
+// Numeric entries are project IDs, everything else is a path such as group/subgroup/project.
+func readProjectsFile(path string) ([]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects file: %w", err)
+	}
+	defer file.Close()
+
+	var ids []any
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+
+		if id, err := strconv.Atoi(line); err == nil {
+			ids = append(ids, id)
+		} else {
+			ids = append(ids, strings.Trim(line, "/"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read projects file: %w", err)
+	}
+	return ids, nil
+}
+
+// listProjectIDs looks up every project of a list file. Projects that don't exist or aren't visible to the token
+// are warned about and skipped, so a stale entry doesn't stop the others from being cloned.
+func listProjectIDs(ctx context.Context, client *gitlab.Client, ids []any, fn func(*gitlab.Project) error) error {
+	for _, id := range ids {
+		project, resp, err := client.Projects.GetProject(id, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				pterm.Warning.Printf("Project %v not found, skipping it\n", id)
+				continue
+			}
+			return fmt.Errorf("failed to get project %v: %w", id, err)
+		}
+
+		if err := fn(project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
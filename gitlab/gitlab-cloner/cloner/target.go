@@ -0,0 +1,182 @@
+package cloner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/xanzy/go-gitlab"
+)
+
+// mirrorTarget is a Git host projects are mirrored to.
+type mirrorTarget interface {
+	// ensure returns the HTTPS clone URL of the repository at path, relative to the target namespace, creating it
+	// and its parent namespaces first if needed.
+	ensure(ctx context.Context, path string) (string, error)
+	// auth authenticates git pushes to the target.
+	auth() transport.AuthMethod
+}
+
+// gitLabTarget mirrors projects under a group of a GitLab instance, recreating the subgroups of the source.
+type gitLabTarget struct {
+	client    *gitlab.Client
+	namespace string
+	token     string
+
+	// mu serialises group creation, as several workers may need the same missing subgroup.
+	mu sync.Mutex
+	// groups holds the IDs of the groups known to exist, by full path.
+	groups map[string]int
+}
+
+func (t *gitLabTarget) ensure(ctx context.Context, rel string) (string, error) {
+	full := t.namespace + "/" + rel
+	project, resp, err := t.client.Projects.GetProject(full, nil, gitlab.WithContext(ctx))
+	if err == nil {
+		return project.HTTPURLToRepo, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return "", fmt.Errorf("failed to get project %s: %w", full, err)
+	}
+
+	t.mu.Lock()
+	groupID, err := t.ensureGroup(ctx, path.Dir(full))
+	t.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	name := path.Base(full)
+	project, _, err = t.client.Projects.CreateProject(&gitlab.CreateProjectOptions{
+		Name:        gitlab.String(name),
+		Path:        gitlab.String(name),
+		NamespaceID: gitlab.Int(groupID),
+		Visibility:  gitlab.Visibility(gitlab.PrivateVisibility),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to create project %s: %w", full, err)
+	}
+	return project.HTTPURLToRepo, nil
+}
+
+// ensureGroup returns the ID of the group at full, creating it and its missing parents as private subgroups.
+// The top level group must exist already. t.mu must be held.
+func (t *gitLabTarget) ensureGroup(ctx context.Context, full string) (int, error) {
+	if id, ok := t.groups[full]; ok {
+		return id, nil
+	}
+
+	group, resp, err := t.client.Groups.GetGroup(full, nil, gitlab.WithContext(ctx))
+	switch {
+	case err == nil:
+	case resp != nil && resp.StatusCode == http.StatusNotFound && strings.Contains(full, "/"):
+		parentID, err := t.ensureGroup(ctx, path.Dir(full))
+		if err != nil {
+			return 0, err
+		}
+		name := path.Base(full)
+		group, _, err = t.client.Groups.CreateGroup(&gitlab.CreateGroupOptions{
+			Name:       gitlab.String(name),
+			Path:       gitlab.String(name),
+			ParentID:   gitlab.Int(parentID),
+			Visibility: gitlab.Visibility(gitlab.PrivateVisibility),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create group %s: %w", full, err)
+		}
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return 0, fmt.Errorf("target group %s not found", full)
+	default:
+		return 0, fmt.Errorf("failed to get group %s: %w", full, err)
+	}
+
+	t.groups[full] = group.ID
+	return group.ID, nil
+}
+
+func (t *gitLabTarget) auth() transport.AuthMethod {
+	return &githttp.BasicAuth{Username: "oauth2", Password: t.token}
+}
+
+// gitHubTarget mirrors projects into a GitHub organization. GitHub has no nested namespaces, so the path of a
+// project is flattened into the repository name, e.g. "team/api" becomes "team-api".
+type gitHubTarget struct {
+	api    string
+	org    string
+	token  string
+	client *http.Client
+}
+
+type gitHubRepository struct {
+	CloneURL string `json:"clone_url"`
+}
+
+func (t *gitHubTarget) ensure(ctx context.Context, rel string) (string, error) {
+	name := strings.ReplaceAll(rel, "/", "-")
+
+	var repo gitHubRepository
+	status, err := t.do(ctx, http.MethodGet, "/repos/"+t.org+"/"+name, nil, &repo)
+	if err != nil || status == http.StatusOK {
+		return repo.CloneURL, err
+	}
+
+	// A missing organization, or one the token can't see, answers 404 here too.
+	status, err = t.do(ctx, http.MethodPost, "/orgs/"+t.org+"/repos", map[string]any{"name": name, "private": true}, &repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to create repository %s/%s: %w", t.org, name, err)
+	}
+	if status != http.StatusCreated {
+		return "", fmt.Errorf("failed to create repository %s in organization %s: %d %s", name, t.org, status, http.StatusText(status))
+	}
+	return repo.CloneURL, nil
+}
+
+// do sends a request to the GitHub API and decodes its response into out. It only fails on statuses other than
+// 2xx and 404, which is returned for the caller to handle.
+func (t *gitHubTarget) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(t.api, "/")+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp.StatusCode, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (t *gitHubTarget) auth() transport.AuthMethod {
+	return &githttp.BasicAuth{Username: "x-access-token", Password: t.token}
+}
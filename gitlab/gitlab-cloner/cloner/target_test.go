@@ -0,0 +1,259 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// fakeGitLabTarget serves the project and group endpoints the GitLab mirror target uses, and records what it
+// creates.
+type fakeGitLabTarget struct {
+	mu       sync.Mutex
+	projects map[string]bool
+	groups   map[string]int
+	created  []string
+}
+
+func (f *fakeGitLabTarget) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v4/projects/"):
+		full := strings.TrimPrefix(r.URL.Path, "/api/v4/projects/")
+		if !f.projects[full] {
+			http.Error(w, `{"message":"404 Project Not Found"}`, http.StatusNotFound)
+			return
+		}
+		f.writeProject(w, full)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v4/groups/"):
+		full := strings.TrimPrefix(r.URL.Path, "/api/v4/groups/")
+		id, ok := f.groups[full]
+		if !ok {
+			http.Error(w, `{"message":"404 Group Not Found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "full_path": full})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v4/groups":
+		var opts struct {
+			Path     string `json:"path"`
+			ParentID int    `json:"parent_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&opts)
+		full := f.groupPath(opts.ParentID) + "/" + opts.Path
+		f.groups[full] = len(f.groups) + 1
+		f.created = append(f.created, "group "+full)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": f.groups[full], "full_path": full})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects":
+		var opts struct {
+			Path        string `json:"path"`
+			NamespaceID int    `json:"namespace_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&opts)
+		full := f.groupPath(opts.NamespaceID) + "/" + opts.Path
+		f.projects[full] = true
+		f.created = append(f.created, "project "+full)
+		w.WriteHeader(http.StatusCreated)
+		f.writeProject(w, full)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitLabTarget) writeProject(w http.ResponseWriter, full string) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"path_with_namespace": full,
+		"http_url_to_repo":    "https://gitlab.example.com/" + full + ".git",
+	})
+}
+
+// groupPath returns the full path of the group with the given ID. f.mu must be held.
+func (f *fakeGitLabTarget) groupPath(id int) string {
+	for full, groupID := range f.groups {
+		if groupID == id {
+			return full
+		}
+	}
+	return ""
+}
+
+func newGitLabTarget(t *testing.T, namespace string, fake *fakeGitLabTarget) *gitLabTarget {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &gitLabTarget{client: client, namespace: namespace, token: "token", groups: map[string]int{}}
+}
+
+func TestGitLabTargetExistingProject(t *testing.T) {
+	fake := &fakeGitLabTarget{projects: map[string]bool{"migrated/team/api": true}, groups: map[string]int{"migrated": 1}}
+	target := newGitLabTarget(t, "migrated", fake)
+
+	url, err := target.ensure(context.Background(), "team/api")
+	if err != nil {
+		t.Fatalf("ensure() error = %v", err)
+	}
+	if url != "https://gitlab.example.com/migrated/team/api.git" {
+		t.Errorf("ensure() = %s, want the URL of the existing project", url)
+	}
+	if len(fake.created) != 0 {
+		t.Errorf("ensure() created %v, want nothing", fake.created)
+	}
+}
+
+func TestGitLabTargetCreatesProjectAndSubgroups(t *testing.T) {
+	fake := &fakeGitLabTarget{projects: map[string]bool{}, groups: map[string]int{"migrated": 1}}
+	target := newGitLabTarget(t, "migrated", fake)
+
+	for _, rel := range []string{"team/backend/api", "team/backend/web"} {
+		url, err := target.ensure(context.Background(), rel)
+		if err != nil {
+			t.Fatalf("ensure(%s) error = %v", rel, err)
+		}
+		if want := "https://gitlab.example.com/migrated/" + rel + ".git"; url != want {
+			t.Errorf("ensure(%s) = %s, want %s", rel, url, want)
+		}
+	}
+
+	want := "group migrated/team,group migrated/team/backend,project migrated/team/backend/api,project migrated/team/backend/web"
+	if got := strings.Join(fake.created, ","); got != want {
+		t.Errorf("created %s, want %s", got, want)
+	}
+}
+
+func TestGitLabTargetMissingNamespace(t *testing.T) {
+	fake := &fakeGitLabTarget{projects: map[string]bool{}, groups: map[string]int{}}
+	target := newGitLabTarget(t, "migrated", fake)
+
+	// The top level group isn't created, it must exist already.
+	if _, err := target.ensure(context.Background(), "api"); err == nil || !strings.Contains(err.Error(), "target group migrated not found") {
+		t.Errorf("ensure() error = %v, want a missing target group error", err)
+	}
+	if len(fake.created) != 0 {
+		t.Errorf("ensure() created %v, want nothing", fake.created)
+	}
+}
+
+// fakeGitHubTarget serves the repository endpoints the GitHub mirror target uses for the organization org, and
+// records the repositories it creates.
+type fakeGitHubTarget struct {
+	org   string
+	repos map[string]bool
+
+	mu      sync.Mutex
+	created []string
+}
+
+func (f *fakeGitHubTarget) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/repos/"):
+		full := strings.TrimPrefix(r.URL.Path, "/repos/")
+		if !f.repos[full] {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		f.writeRepository(w, full)
+	case r.Method == http.MethodPost && r.URL.Path == "/orgs/"+f.org+"/repos":
+		var opts struct {
+			Name    string `json:"name"`
+			Private bool   `json:"private"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&opts)
+		if !opts.Private {
+			http.Error(w, `{"message":"mirrors must be private"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		full := f.org + "/" + opts.Name
+		f.repos[full] = true
+		f.created = append(f.created, full)
+		w.WriteHeader(http.StatusCreated)
+		f.writeRepository(w, full)
+	default:
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitHubTarget) writeRepository(w http.ResponseWriter, full string) {
+	_ = json.NewEncoder(w).Encode(map[string]any{"clone_url": "https://github.com/" + full + ".git"})
+}
+
+func newGitHubTarget(t *testing.T, org string, fake *fakeGitHubTarget) *gitHubTarget {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return &gitHubTarget{api: server.URL, org: org, token: "token", client: server.Client()}
+}
+
+func TestGitHubTargetExistingRepository(t *testing.T) {
+	fake := &fakeGitHubTarget{org: "acme", repos: map[string]bool{"acme/team-api": true}}
+	target := newGitHubTarget(t, "acme", fake)
+
+	url, err := target.ensure(context.Background(), "team/api")
+	if err != nil {
+		t.Fatalf("ensure() error = %v", err)
+	}
+	if url != "https://github.com/acme/team-api.git" {
+		t.Errorf("ensure() = %s, want the URL of the existing repository", url)
+	}
+	if len(fake.created) != 0 {
+		t.Errorf("ensure() created %v, want nothing", fake.created)
+	}
+}
+
+func TestGitHubTargetCreatesRepository(t *testing.T) {
+	fake := &fakeGitHubTarget{org: "acme", repos: map[string]bool{}}
+	target := newGitHubTarget(t, "acme", fake)
+
+	url, err := target.ensure(context.Background(), "team/api")
+	if err != nil {
+		t.Fatalf("ensure() error = %v", err)
+	}
+	if url != "https://github.com/acme/team-api.git" {
+		t.Errorf("ensure() = %s, want the URL of the created repository", url)
+	}
+	if got := strings.Join(fake.created, ","); got != "acme/team-api" {
+		t.Errorf("created %s, want acme/team-api", got)
+	}
+}
+
+func TestGitHubTargetMissingOrganization(t *testing.T) {
+	fake := &fakeGitHubTarget{org: "acme", repos: map[string]bool{}}
+	target := newGitHubTarget(t, "missing", fake)
+
+	url, err := target.ensure(context.Background(), "team/api")
+	if err == nil || !strings.Contains(err.Error(), "organization missing") {
+		t.Errorf("ensure() = %q, %v, want an error naming the organization", url, err)
+	}
+}
+
+func TestGitHubTargetBadCredentials(t *testing.T) {
+	fake := &fakeGitHubTarget{org: "acme", repos: map[string]bool{}}
+	target := newGitHubTarget(t, "acme", fake)
+	target.token = "wrong"
+
+	if _, err := target.ensure(context.Background(), "team/api"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ensure() error = %v, want a 401 error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package cloner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	transportSSH   = "ssh"
+	transportHTTPS = "https"
+)
+
+var transportFlag = flags.String("transport", transportSSH, "git transport: ssh, or https authenticated with GITLAB_PRIVATE_TOKEN")
+var sshKeyFlag = flags.String("ssh-key", "", "private key used by the ssh transport instead of the SSH agent, its passphrase is read from GITLAB_SSH_KEY_PASSPHRASE")
+
+// gitAuth returns the credentials of the git operations for the selected transport.
+func gitAuth(token string) (transport.AuthMethod, error) {
+	switch *transportFlag {
+	case transportHTTPS:
+		// GitLab accepts personal, project and group access tokens as the password of the oauth2 user.
+		return &http.BasicAuth{Username: "oauth2", Password: token}, nil
+	case transportSSH:
+		if *sshKeyFlag != "" {
+			auth, err := ssh.NewPublicKeysFromFile("git", *sshKeyFlag, os.Getenv("GITLAB_SSH_KEY_PASSPHRASE"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load SSH key %s: %w", *sshKeyFlag, err)
+			}
+			return auth, nil
+		}
+		auth, err := ssh.NewSSHAgentAuth("git")
+		if err != nil {
+			return nil, fmt.Errorf("failed to use the SSH agent, set -ssh-key or use -transport https: %w", err)
+		}
+		return auth, nil
+	default:
+		return nil, fmt.Errorf("invalid transport %q, expected ssh or https", *transportFlag)
+	}
+}
+
+// cloneURL returns the URL of project for the selected transport.
+func cloneURL(project *gitlab.Project) string {
+	if *transportFlag == transportHTTPS {
+		return project.HTTPURLToRepo
+	}
+	return project.SSHURLToRepo
+}
@@ -0,0 +1,73 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Excoriate/dxutils/pkg/reposync"
+	"github.com/xanzy/go-gitlab"
+)
+
+var wikisFlag = flags.Bool("wikis", false, "also clone the wiki of every project that has it enabled, next to the project directory")
+
+var groupWikisFlag = flags.Bool("group-wikis", false, "also clone the wikis of the group and its subgroups (GitLab Premium)")
+
+// wikiSuffix is appended by GitLab to the path of a project or group to name its wiki repository.
+const wikiSuffix = ".wiki"
+
+func validateWikis() error {
+	if *groupWikisFlag && *groupPath == "" {
+		return errors.New("-group-wikis can only be used with -group")
+	}
+	return nil
+}
+
+// projectWiki returns the wiki repository of a project, cloned next to it as <project>.wiki.
+func projectWiki(project *gitlab.Project) reposync.Repository {
+	repo := reposync.Repository{
+		Path:     project.PathWithNamespace + wikiSuffix,
+		FullName: project.PathWithNamespace + wikiSuffix,
+		CloneURL: strings.TrimSuffix(cloneURL(project), ".git") + wikiSuffix + ".git",
+		Archived: project.Archived,
+	}
+	if project.LastActivityAt != nil {
+		repo.PushedAt = *project.LastActivityAt
+	}
+	return repo
+}
+
+// cloneRoot returns the part of a project's clone URL that precedes repository paths, e.g.
+// "git@gitlab.example.com:" or "https://gitlab.example.com/". GitLab doesn't report the clone URL of group
+// wikis, so they are built from it.
+func cloneRoot(project *gitlab.Project) string {
+	return strings.TrimSuffix(cloneURL(project), project.PathWithNamespace+".git")
+}
+
+// listGroupWikis calls fn for the wiki of the group and of each of its subgroups. Groups report their wiki
+// access level only on Premium instances, so nothing is listed elsewhere.
+func listGroupWikis(ctx context.Context, client *gitlab.Client, group, root string, fn func(reposync.Repository) error) error {
+	wiki := func(g *gitlab.Group) error {
+		if g.WikiAccessLevel == "" || g.WikiAccessLevel == gitlab.DisabledAccessControl {
+			return nil
+		}
+		return fn(reposync.Repository{
+			Path:     g.FullPath + wikiSuffix,
+			FullName: g.FullPath + wikiSuffix,
+			CloneURL: root + g.FullPath + wikiSuffix + ".git",
+		})
+	}
+
+	top, _, err := client.Groups.GetGroup(group, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if err := wiki(top); err != nil {
+		return err
+	}
+
+	opt := &gitlab.ListDescendantGroupsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	return paginate(ctx, wiki, func(opts ...gitlab.RequestOptionFunc) ([]*gitlab.Group, *gitlab.Response, error) {
+		return client.Groups.ListDescendantGroups(group, opt, opts...)
+	})
+}
@@ -3,7 +3,9 @@ module github.com/Excoriate/dxutils/gitlab/gitlab-cloner
 go 1.21.1
 
 require (
+	github.com/Excoriate/dxutils/pkg/reposync v0.0.0
 	github.com/go-git/go-git/v5 v5.10.0
+	github.com/hashicorp/go-retryablehttp v0.7.2
 	github.com/pterm/pterm v0.12.69
 	github.com/xanzy/go-gitlab v0.93.2
 )
@@ -27,7 +29,6 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/gookit/color v1.5.4 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
-	github.com/hashicorp/go-retryablehttp v0.7.2 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/kevinburke/ssh_config v1.2.0 // indirect
 	github.com/lithammer/fuzzysearch v1.1.8 // indirect
@@ -51,3 +52,5 @@ require (
 	google.golang.org/protobuf v1.29.1 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/Excoriate/dxutils/pkg/reposync => ../../pkg/reposync
@@ -0,0 +1,173 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/pterm/pterm"
+	"github.com/xanzy/go-gitlab"
+)
+
+var wg sync.WaitGroup
+var excludeFlag string
+var moduleFlag string
+var reposWithModule []string
+var reposWithoutModule []string
+var reposExcluded []string
+var mu sync.Mutex // Mutex to safely append to the slices
+
+// flags holds every option of the inspector. The standalone binary parses it from the command line, and
+// dxutils exposes it on its "tf inspect" command.
+var flags = flag.NewFlagSet("gitlab-tf-module-inspector", flag.ExitOnError)
+
+var groupFlag = flags.String("group", "", "The GitLab group/subgroup path (required)")
+
+func init() {
+	flags.StringVar(&moduleFlag, "tf-module", "", "Terraform module source string to search for (required)")
+	flags.StringVar(&excludeFlag, "exclude", "", "String to exclude projects from analysis")
+}
+
+// Flags returns the flag set Run reads its options from. It must be parsed before calling Run.
+func Flags() *flag.FlagSet {
+	return flags
+}
+
+// Run searches the projects of the group for the Terraform module and prints a summary table.
+func Run(ctx context.Context) error {
+	token := os.Getenv("GITLAB_PRIVATE_TOKEN")
+	if token == "" {
+		return errors.New("GITLAB_PRIVATE_TOKEN environment variable is not set")
+	}
+	gitLabClient, err := gitlab.NewClient(token)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	if *groupFlag == "" || moduleFlag == "" {
+		return errors.New("both -group and -tf-module are required")
+	}
+
+	reposWithModule, reposWithoutModule, reposExcluded = nil, nil, nil
+
+	pterm.DefaultSection.Println(fmt.Sprintf("Searching for Terraform module usage in repositories under %s...", *groupFlag))
+	err = searchRepositories(ctx, gitLabClient, *groupFlag, excludeFlag)
+
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("error fetching projects: %w", err)
+	}
+
+	totalProjects := len(reposWithModule) + len(reposWithoutModule) + len(reposExcluded)
+	pterm.DefaultSection.Printf("Total Projects Evaluated: %d\nRepos Using the Module: %d\nRepos Not Using the Module: %d\nExcluded Repos: %d\n",
+		totalProjects, len(reposWithModule), len(reposWithoutModule), len(reposExcluded))
+
+	pterm.DefaultSection.Println("Search Summary")
+	createSummaryTable()
+	return nil
+}
+
+func createSummaryTable() {
+	reposTable := pterm.TableData{{"Project Path", "Uses Module", "Excluded"}}
+
+	addToTable := func(repoList []string, usesModule string, excluded string) {
+		for _, repo := range repoList {
+			reposTable = append(reposTable, []string{repo, usesModule, excluded})
+		}
+	}
+
+	addToTable(reposWithModule, "Yes", "")
+	addToTable(reposWithoutModule, "No", "")
+	addToTable(reposExcluded, "-", "Yes")
+
+	pterm.DefaultTable.WithHasHeader().WithData(reposTable).Render()
+}
+
+// searchRepositories starts a search of every project of the group. Callers wait on wg for the searches.
+func searchRepositories(ctx context.Context, client *gitlab.Client, group string, exclude string) error {
+	opt := &gitlab.ListGroupProjectsOptions{
+		ListOptions:      gitlab.ListOptions{PerPage: 10},
+		IncludeSubGroups: gitlab.Bool(true),
+	}
+	for {
+		projects, resp, err := client.Groups.ListGroupProjects(group, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+
+		for _, project := range projects {
+			if exclude != "" && strings.Contains(project.PathWithNamespace, exclude) {
+				mu.Lock()
+				reposExcluded = append(reposExcluded, project.PathWithNamespace)
+				mu.Unlock()
+				continue
+			}
+			wg.Add(1)
+			go searchProjectForModule(project, moduleFlag)
+		}
+
+		if resp.CurrentPage >= resp.TotalPages {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return nil
+}
+
+func searchProjectForModule(project *gitlab.Project, module string) {
+	defer wg.Done()
+
+	r, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL: project.SSHURLToRepo,
+	})
+
+	if err != nil {
+		pterm.Error.Println("Error cloning project:", err)
+		return
+	}
+
+	ref, err := r.Head()
+	if err != nil {
+		pterm.Error.Println("Error getting HEAD reference:", err)
+		return
+	}
+
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		pterm.Error.Println("Error getting commit object:", err)
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		pterm.Error.Println("Error getting commit tree:", err)
+		return
+	}
+
+	containsModule := false
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if strings.HasSuffix(f.Name, ".tf") {
+			content, _ := f.Contents()
+			if strings.Contains(content, module) {
+				containsModule = true
+				return nil
+			}
+		}
+		return nil
+	})
+
+	mu.Lock() // Lock the mutex to safely access the slices
+	if containsModule {
+		reposWithModule = append(reposWithModule, project.PathWithNamespace)
+	} else {
+		reposWithoutModule = append(reposWithoutModule, project.PathWithNamespace)
+	}
+	mu.Unlock() // Unlock the mutex
+}
@@ -0,0 +1,366 @@
+package reposync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Action tells whether a repository was cloned or pulled.
+type Action string
+
+const (
+	ActionClone Action = "clone"
+	ActionPull  Action = "pull"
+)
+
+// IsGitRepo checks if the .git directory exists under path.
+func IsGitRepo(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return !os.IsNotExist(err)
+}
+
+// CloneOrPull clones the repository of task if its directory is not a git repository yet, and otherwise fetches
+// origin, checks out task.Branch and hard resets it to the remote branch. Both are aborted after task.Timeout, or as soon as ctx
+// is cancelled. Empty repositories are initialised instead, see Repository.Empty.
+func CloneOrPull(ctx context.Context, task Task, progress io.Writer) (Action, error) {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	if task.Empty {
+		return initEmpty(task)
+	}
+
+	if !IsGitRepo(task.Dir) { // If not exists, it is not a git repository, so clone.
+		return ActionClone, cloneWithTimeout(ctx, task, progress)
+	}
+
+	// Else, it's already a repository, try pull.
+	return ActionPull, pullWithTimeout(ctx, task, progress)
+}
+
+// initEmpty initialises the clone of an empty repository with origin configured and HEAD on task.Branch, or
+// only updates origin if it was initialised already. There is nothing to fetch yet.
+func initEmpty(task Task) (Action, error) {
+	if IsGitRepo(task.Dir) {
+		r, err := git.PlainOpen(task.Dir)
+		if err != nil {
+			return ActionPull, err
+		}
+		return ActionPull, setOriginURL(r, task.CloneURL)
+	}
+
+	r, err := git.PlainInit(task.Dir, false)
+	if err != nil {
+		return ActionClone, err
+	}
+	if _, err := r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{task.CloneURL}}); err != nil {
+		return ActionClone, err
+	}
+
+	branch := plumbing.NewBranchReferenceName(task.Branch)
+	cfg, err := r.Config()
+	if err != nil {
+		return ActionClone, err
+	}
+	cfg.Branches[task.Branch] = &config.Branch{Name: task.Branch, Remote: "origin", Merge: branch}
+	if err := r.SetConfig(cfg); err != nil {
+		return ActionClone, err
+	}
+	return ActionClone, r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+}
+
+// RemoteHEAD returns the branch the HEAD of the task's remote points at, i.e. its actual default branch.
+// It fails with transport.ErrEmptyRemoteRepository if the remote has no commits yet.
+func RemoteHEAD(ctx context.Context, task Task) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{task.CloneURL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: task.Auth, CABundle: task.CABundle})
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return ref.Target().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("HEAD of %s does not point at a branch", task.CloneURL)
+}
+
+// cloneWithTimeout attempts to clone a repository to its destination path, but will time out and abort the
+// operation if it takes too long.
+func cloneWithTimeout(ctx context.Context, task Task, progress io.Writer) error {
+	ch := make(chan error, 1)
+	go func() {
+		r, err := git.PlainCloneContext(ctx, task.Dir, false, &git.CloneOptions{
+			URL:           task.CloneURL,
+			Auth:          task.Auth,
+			CABundle:      task.CABundle,
+			Progress:      progress,
+			ReferenceName: plumbing.NewBranchReferenceName(task.Branch),
+			SingleBranch:  false,
+			NoCheckout:    len(task.Sparse) > 0,
+		})
+		if err == nil && len(task.Sparse) > 0 {
+			err = sparseCheckout(r, task.Dir, task.Branch, task.Sparse)
+		}
+		ch <- err
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+		return fmt.Errorf("cloning %s timed out", task.CloneURL) // If we timed out, return an error.
+	}
+}
+
+func pullWithTimeout(ctx context.Context, task Task, progress io.Writer) error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- pull(ctx, task, progress)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+		return fmt.Errorf("pulling in %s timed out", task.Dir)
+	}
+}
+
+func pull(ctx context.Context, task Task, progress io.Writer) error {
+	r, err := git.PlainOpen(task.Dir)
+	if err != nil {
+		return err
+	}
+	if err := setOriginURL(r, task.CloneURL); err != nil {
+		return err
+	}
+
+	// Fetch the latest commits from the origin remote
+	err = r.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Auth:       task.Auth,
+		CABundle:   task.CABundle,
+		Progress:   progress,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	// Gets the hash of the remote default branch
+	ref, err := r.Reference(plumbing.NewRemoteReferenceName("origin", task.Branch), true)
+	if err != nil {
+		return err
+	}
+
+	if err := checkoutBranch(r, task.Branch, ref.Hash()); err != nil {
+		return err
+	}
+
+	// A plain reset would materialise the whole tree, so keep the sparse patterns if there are any.
+	patterns, err := resolveSparse(r, task.Dir, task.Sparse)
+	if err != nil {
+		return err
+	}
+
+	// Reset the current working directory to the fetched hash
+	return ResetSparse(r, ref.Hash(), patterns)
+}
+
+// checkoutBranch points HEAD at the local branch, creating it at commit if needed, so that the following reset
+// moves that branch rather than whatever HEAD is on. A clone initialised from an empty repository has no local
+// branch yet, and a caller checking out another ref, e.g. a tag or release branch, leaves HEAD elsewhere.
+func checkoutBranch(r *git.Repository, branch string, commit plumbing.Hash) error {
+	name := plumbing.NewBranchReferenceName(branch)
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Storer.Reference(name)
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		if err := r.Storer.SetReference(plumbing.NewHashReference(name, commit)); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case head.Type() == plumbing.SymbolicReference && head.Target() == name:
+		return nil
+	}
+	return r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, name))
+}
+
+// setOriginURL keeps origin pointing at the listed clone URL, which changes when switching between the SSH and
+// HTTPS transports or when a repository is moved.
+func setOriginURL(r *git.Repository, url string) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	origin, ok := cfg.Remotes["origin"]
+	if !ok || url == "" || (len(origin.URLs) == 1 && origin.URLs[0] == url) {
+		return nil
+	}
+	origin.URLs = []string{url}
+	return r.SetConfig(cfg)
+}
+
+func sparseFile(path string) string {
+	return filepath.Join(path, ".git", "info", "sparse-checkout")
+}
+
+// SparsePatterns returns the sparse patterns recorded in the clone at path by a previous sync, or nil for a full
+// checkout. Callers moving the worktree must pass them on, so that it is not materialised entirely again.
+func SparsePatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(sparseFile(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if pattern := strings.Trim(strings.TrimSpace(line), "/"); pattern != "" && !strings.HasPrefix(pattern, "#") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// ClearSparse forgets the sparse patterns recorded in the clone at path, so that the next sync checks out the
+// whole tree again.
+func ClearSparse(path string) error {
+	if err := os.Remove(sparseFile(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	r, err := git.PlainOpen(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section("core").RemoveOption("sparseCheckout")
+	return r.SetConfig(cfg)
+}
+
+// persistSparse records the sparse patterns the same way git sparse-checkout does, so git itself keeps honouring
+// them in the clone.
+func persistSparse(r *git.Repository, path string, patterns []string) error {
+	var b strings.Builder
+	for _, pattern := range patterns {
+		b.WriteString("/" + pattern + "\n")
+	}
+	if err := os.MkdirAll(filepath.Dir(sparseFile(path)), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(sparseFile(path), []byte(b.String()), 0644); err != nil {
+		return err
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section("core").SetOption("sparseCheckout", "true")
+	return r.SetConfig(cfg)
+}
+
+// resolveSparse returns the patterns to materialise, preferring the configured ones over the ones persisted by a
+// previous sync.
+func resolveSparse(r *git.Repository, path string, configured []string) ([]string, error) {
+	if len(configured) == 0 {
+		return SparsePatterns(path)
+	}
+	patterns := make([]string, 0, len(configured))
+	for _, pattern := range configured {
+		patterns = append(patterns, strings.Trim(strings.TrimSpace(pattern), "/"))
+	}
+	return patterns, persistSparse(r, path, patterns)
+}
+
+// sparseCheckout materialises only the paths of branch matching patterns, in a clone made with NoCheckout.
+func sparseCheckout(r *git.Repository, path, branch string, patterns []string) error {
+	patterns, err := resolveSparse(r, path, patterns)
+	if err != nil {
+		return err
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return err
+	}
+	return ResetSparse(r, ref.Hash(), patterns)
+}
+
+// ResetSparse hard resets the worktree of r to commit, materialising only the paths matching patterns, see
+// MatchSparse. Paths that no longer match are removed from the worktree, and no patterns restore the whole tree.
+func ResetSparse(r *git.Repository, commit plumbing.Hash, patterns []string) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 && !slices.ContainsFunc(idx.Entries, func(e *index.Entry) bool { return e.SkipWorktree }) {
+		return w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset})
+	}
+
+	// go-git only skips directory prefixes, and fails to reset entries it newly skips whose files are missing. So
+	// the index is moved to commit first, its entries flagged here, and the worktree reset against it.
+	if err := w.Reset(&git.ResetOptions{Commit: commit, Mode: git.MixedReset}); err != nil {
+		return err
+	}
+	if idx, err = r.Storer.Index(); err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		e.SkipWorktree = len(patterns) > 0 && !MatchSparse(patterns, e.Name)
+	}
+	if err := r.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+	return w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset})
+}
+
+// MatchSparse reports whether the file at name, relative to the repository root, is materialised by patterns.
+// Each pattern is matched with path.Match against the file and each of its parent directories, so "infra" keeps
+// a whole directory, "modules/*-api" every matching directory under modules, and "*.md" the top level Markdown
+// files.
+func MatchSparse(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		for p := name; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package reposync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestMatchSparse(t *testing.T) {
+	patterns := []string{"infra", "modules/*-api", "*.md"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"infra/main.tf", true},
+		{"infra/envs/prod/main.tf", true},
+		{"infrastructure/main.tf", false},
+		{"modules/users-api/main.tf", true},
+		{"modules/users-web/main.tf", false},
+		{"README.md", true},
+		{"docs/README.md", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := MatchSparse(patterns, tt.name); got != tt.want {
+			t.Errorf("MatchSparse(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSyncSparse(t *testing.T) {
+	dir := t.TempDir()
+	origin := newOrigin(t, dir, "origin")
+	r, err := git.PlainOpen(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"infra/main.tf", "modules/users-api/main.tf", "modules/users-web/main.tf"} {
+		if err := os.MkdirAll(filepath.Join(origin, filepath.Dir(file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		commitFile(t, r, origin, file, file)
+	}
+
+	syncer := New(&fakeProvider{}, Options{BaseDir: dir})
+	task := syncer.NewTask(Repository{Path: "clone", CloneURL: origin, DefaultBranch: "master"})
+	task.Sparse = []string{"infra", "modules/*-api"}
+	clone := filepath.Join(dir, "clone")
+
+	check := func(step string, want map[string]bool) {
+		t.Helper()
+		for file, present := range want {
+			if _, err := os.Stat(filepath.Join(clone, file)); (err == nil) != present {
+				t.Errorf("%s: %s present = %v, want %v", step, file, err == nil, present)
+			}
+		}
+	}
+
+	if res := syncer.Sync(context.Background(), task); res.Err != nil {
+		t.Fatalf("clone: %v", res.Err)
+	}
+	check("clone", map[string]bool{
+		"infra/main.tf":             true,
+		"modules/users-api/main.tf": true,
+		"modules/users-web/main.tf": false,
+		"README.md":                 false,
+	})
+
+	// Files added upstream are only materialised if they match, even without configuring the patterns again.
+	if err := os.MkdirAll(filepath.Join(origin, "modules/orders-api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, r, origin, "modules/orders-api/main.tf", "orders")
+	commitFile(t, r, origin, "main.go", "package main")
+	task.Sparse = nil
+	if res := syncer.Sync(context.Background(), task); res.Err != nil {
+		t.Fatalf("pull: %v", res.Err)
+	}
+	check("pull", map[string]bool{
+		"modules/orders-api/main.tf": true,
+		"main.go":                    false,
+	})
+
+	if err := ClearSparse(clone); err != nil {
+		t.Fatal(err)
+	}
+	if res := syncer.Sync(context.Background(), task); res.Err != nil {
+		t.Fatalf("pull after clear: %v", res.Err)
+	}
+	check("pull after clear", map[string]bool{
+		"modules/users-web/main.tf": true,
+		"README.md":                 true,
+		"main.go":                   true,
+	})
+}
@@ -0,0 +1,41 @@
+// Package reposync keeps local clones of the repositories listed by a git hosting provider in sync. It owns the
+// worker pool, the git operations, the progress reporting and the results, so each cloner only has to implement
+// a Provider.
+package reposync
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Repository is a repository listed by a Provider.
+type Repository struct {
+	// Path is where the repository is cloned, relative to the base directory, e.g. "group/subgroup/project".
+	Path string
+	// FullName identifies the repository on the provider, e.g. "org/repo".
+	FullName string
+	// CloneURL is the URL git clones and fetches from.
+	CloneURL string
+	// DefaultBranch may be empty, in which case Options.FallbackBranch is used.
+	DefaultBranch string
+	Archived      bool
+	// Empty repositories have no commits yet. They are initialised with origin configured instead of cloned, so
+	// later syncs pick up their first commits.
+	Empty bool
+	// SizeKB is the size reported by the provider, zero if unknown.
+	SizeKB   int64
+	PushedAt time.Time
+}
+
+// Provider lists the repositories of a git hosting service and authenticates git against it.
+type Provider interface {
+	// Name identifies the provider in logs and progress titles, e.g. "GitHub".
+	Name() string
+	// List calls fn for every repository as soon as it is listed, so cloning can start while later pages are
+	// still being fetched. Listing stops at the first error returned by fn.
+	List(ctx context.Context, fn func(Repository) error) error
+	// Auth returns the credentials for git operations on repo, or nil if none are needed.
+	Auth(repo Repository) transport.AuthMethod
+}
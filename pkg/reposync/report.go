@@ -0,0 +1,137 @@
+package reposync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Reporter is notified of the progress of a Syncer. It is called from the listing and worker goroutines
+// concurrently, so implementations must be safe for concurrent use.
+type Reporter interface {
+	// Listed is called once listing is over, with the number of repositories listed and the listing error.
+	Listed(count int, elapsed time.Duration, err error)
+	// Queued is called for every repository accepted for sync. Each one gets a Finished call later.
+	Queued(task Task)
+	// Started is called right before a repository is cloned or pulled.
+	Started(task Task, action Action)
+	// Finished is called with the outcome of every queued repository.
+	Finished(res Result)
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Listed(int, time.Duration, error) {}
+func (nopReporter) Queued(Task)                      {}
+func (nopReporter) Started(Task, Action)             {}
+func (nopReporter) Finished(Result)                  {}
+
+// ProgressBar is a progress bar whose total grows as repositories are queued. It is safe for concurrent use, so
+// reporters can drive it from the listing and worker goroutines.
+type ProgressBar struct {
+	mu    sync.Mutex
+	bar   *pterm.ProgressbarPrinter
+	total int
+	// listed is set once listing is over. Until then the bar counts one more repository than queued, as pterm
+	// stops it for good when the workers catch up with a listing that is still fetching pages.
+	listed bool
+}
+
+// NewProgressBar starts a progress bar with the given title.
+func NewProgressBar(title string) *ProgressBar {
+	// The elapsed time is re-rendered by a pterm ticker that doesn't take our lock, so it's left out
+	// to keep concurrent workers race-free.
+	bar, _ := pterm.DefaultProgressbar.WithTitle(title).WithTotal(0).WithShowElapsedTime(false).Start()
+	return &ProgressBar{bar: bar}
+}
+
+// Queued counts one more repository in the total.
+func (b *ProgressBar) Queued() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total++
+	// WithTotal returns a copy, so the running bar has to be updated in place.
+	b.bar.Total = b.total
+	if !b.listed {
+		b.bar.Total++
+	}
+}
+
+// Listed settles the total once listing is over.
+func (b *ProgressBar) Listed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.listed = true
+	b.bar.Total = b.total
+	// Renders the final total, and stops the bar if every repository was synced already.
+	b.bar.Add(0)
+}
+
+// Title replaces the title of the bar, e.g. with the repository being synced.
+func (b *ProgressBar) Title(title string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bar.UpdateTitle(title)
+}
+
+// Increment counts one more repository as finished.
+func (b *ProgressBar) Increment() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bar.Increment()
+}
+
+// Stop stops the progress bar.
+func (b *ProgressBar) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, _ = b.bar.Stop()
+}
+
+// ProgressReporter shows a ProgressBar and prints failures.
+type ProgressReporter struct {
+	bar *ProgressBar
+}
+
+// NewProgressReporter starts a progress bar with the given title.
+func NewProgressReporter(title string) *ProgressReporter {
+	return &ProgressReporter{bar: NewProgressBar(title)}
+}
+
+func (p *ProgressReporter) Listed(count int, elapsed time.Duration, err error) {
+	p.bar.Listed()
+	if err != nil {
+		pterm.Error.Printf("Failed to list repositories: %v\n", err)
+	}
+}
+
+func (p *ProgressReporter) Queued(task Task) {
+	p.bar.Queued()
+}
+
+func (p *ProgressReporter) Started(task Task, action Action) {
+	p.bar.Title("Cloning " + task.FullName)
+}
+
+func (p *ProgressReporter) Finished(res Result) {
+	switch {
+	case res.Status == StatusFailed && res.Retries > 0:
+		pterm.Warning.Printf("Failed to clone or update %s after %d retries: %v\n", res.Task.FullName, res.Retries, res.Err)
+	case res.Status == StatusFailed:
+		pterm.Warning.Printf("Failed to clone or update %s: %v\n", res.Task.FullName, res.Err)
+	case res.Retries > 0:
+		pterm.Info.Printf("Synced %s after %d retries\n", res.Task.FullName, res.Retries)
+	}
+	p.bar.Increment()
+}
+
+// Stop stops the progress bar.
+func (p *ProgressReporter) Stop() {
+	p.bar.Stop()
+}
@@ -0,0 +1,25 @@
+package reposync
+
+import "testing"
+
+func TestProgressBarOutlivesEarlyFinishes(t *testing.T) {
+	bar := NewProgressBar("test")
+	defer bar.Stop()
+
+	// A repository skipped by Prepare finishes before the next one is even listed.
+	bar.Queued()
+	bar.Increment()
+	if !bar.bar.IsActive {
+		t.Fatal("bar stopped while listing was still going on")
+	}
+
+	bar.Queued()
+	bar.Listed()
+	if !bar.bar.IsActive {
+		t.Fatal("bar stopped with a repository left to sync")
+	}
+	bar.Increment()
+	if bar.bar.IsActive {
+		t.Error("bar still running once every repository finished")
+	}
+}
@@ -0,0 +1,72 @@
+package reposync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// maxRetryDelay caps the exponential backoff between retries.
+const maxRetryDelay = 30 * time.Second
+
+// transientMessages are found in git errors that don't wrap their cause, such as the ones of the SSH transport.
+var transientMessages = []string{"connection reset", "broken pipe", "unexpected EOF", "early EOF", "TLS handshake timeout"}
+
+// IsTransient reports whether a git error is likely to go away when retried, such as a dropped connection or a
+// server error, as opposed to missing credentials or repositories. Timeouts are not, as a retry would most likely
+// time out again.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// The HTTP transport reports server errors wrapped in an error without Unwrap.
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var httpErr *githttp.Err
+		if errors.As(unexpected.Err, &httpErr) {
+			code := httpErr.StatusCode()
+			return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+		}
+		err = unexpected.Err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	for _, msg := range transientMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not transient, or was retried retries times,
+// waiting with an exponential backoff in between. It returns the number of retries and the last error.
+func Retry(ctx context.Context, retries int, fn func() error) (int, error) {
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if attempt >= retries || !IsTransient(err) {
+			return attempt, err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return attempt, err
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
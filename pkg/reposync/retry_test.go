@@ -0,0 +1,101 @@
+package reposync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// httpErr builds the error the HTTP transport returns for a response with the given status.
+func httpErr(status int) error {
+	req, _ := http.NewRequest(http.MethodGet, "https://git.example.com/org/repo.git/info/refs", nil)
+	return githttp.NewErr(&http.Response{StatusCode: status, Request: req})
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"timeout", fmt.Errorf("fetch: %w", context.DeadlineExceeded), false},
+		{"cancelled", context.Canceled, false},
+		{"not found", transport.ErrRepositoryNotFound, false},
+		{"auth", transport.ErrAuthenticationRequired, false},
+		{"server error", httpErr(http.StatusBadGateway), true},
+		{"rate limited", httpErr(http.StatusTooManyRequests), true},
+		{"forbidden", httpErr(http.StatusForbidden), false},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"network", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"unexpected EOF", fmt.Errorf("pack: %w", io.ErrUnexpectedEOF), true},
+		{"ssh message", errors.New("ssh: handshake failed: connection reset by peer"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	transient := fmt.Errorf("read: %w", syscall.ECONNRESET)
+
+	t.Run("succeeds after a transient error", func(t *testing.T) {
+		calls := 0
+		retries, err := Retry(context.Background(), 3, func() error {
+			if calls++; calls == 1 {
+				return transient
+			}
+			return nil
+		})
+		if err != nil || retries != 1 || calls != 2 {
+			t.Errorf("Retry() = %d, %v after %d calls, want 1, nil after 2 calls", retries, err, calls)
+		}
+	})
+
+	t.Run("doesn't retry permanent errors", func(t *testing.T) {
+		calls := 0
+		retries, err := Retry(context.Background(), 3, func() error {
+			calls++
+			return transport.ErrRepositoryNotFound
+		})
+		if !errors.Is(err, transport.ErrRepositoryNotFound) || retries != 0 || calls != 1 {
+			t.Errorf("Retry() = %d, %v after %d calls, want 0, not found after 1 call", retries, err, calls)
+		}
+	})
+
+	t.Run("gives up without retries left", func(t *testing.T) {
+		calls := 0
+		retries, err := Retry(context.Background(), 0, func() error {
+			calls++
+			return transient
+		})
+		if !errors.Is(err, transient) || retries != 0 || calls != 1 {
+			t.Errorf("Retry() = %d, %v after %d calls, want 0, the transient error after 1 call", retries, err, calls)
+		}
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		retries, err := Retry(ctx, 3, func() error {
+			calls++
+			return transient
+		})
+		if !errors.Is(err, transient) || retries != 0 || calls != 1 {
+			t.Errorf("Retry() = %d, %v after %d calls, want 0, the transient error after 1 call", retries, err, calls)
+		}
+	})
+}
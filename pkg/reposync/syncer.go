@@ -0,0 +1,276 @@
+package reposync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Status is the outcome of a repository sync. Hooks may set their own statuses on a Result.
+type Status string
+
+const (
+	StatusUpdated   Status = "updated"
+	StatusUnchanged Status = "unchanged"
+	StatusSkipped   Status = "skipped"
+	StatusFailed    Status = "failed"
+)
+
+// Task is a repository queued for sync, with everything the git operations need.
+type Task struct {
+	Repository
+	// Dir is where the repository is cloned, Options.BaseDir joined with Path by default.
+	Dir string
+	// Branch is checked out and kept in sync with origin.
+	Branch string
+	// Sparse lists the path patterns to materialise, empty for a full checkout, see MatchSparse.
+	Sparse  []string
+	Timeout time.Duration
+	Auth    transport.AuthMethod
+	// CABundle holds extra PEM certificates trusted on top of the system pool for HTTPS remotes.
+	CABundle []byte
+	// Data carries caller specific values between the hooks, e.g. the provider's own repository type.
+	Data any
+}
+
+// Result is the outcome of the sync of a single repository.
+type Result struct {
+	Task   Task
+	Status Status
+	Action Action
+	// Reason explains why a repository was skipped or left unchanged.
+	Reason   string
+	Err      error
+	Duration time.Duration
+	// Retries counts the attempts that failed with a transient error before the last one.
+	Retries int
+	// Fields are extra values reported by the hooks, e.g. the checked out ref.
+	Fields map[string]any
+}
+
+// Skip is returned by the hooks to leave a repository out of the sync with the given status.
+type Skip struct {
+	Status Status
+	Reason string
+}
+
+func (s *Skip) Error() string {
+	return s.Reason
+}
+
+// Options configures a Syncer.
+type Options struct {
+	BaseDir string
+	// Workers is the number of repositories synced concurrently, 1 if unset.
+	Workers int
+	// QueueSize is the number of listed repositories buffered ahead of the workers, 1000 if unset.
+	QueueSize int
+	// Timeout applies to the git operations of every repository, 2 minutes if unset.
+	Timeout time.Duration
+	// FallbackBranch is used for repositories without a default branch, "main" if unset.
+	FallbackBranch string
+	// Retries is how many times a clone or pull failing with a transient error is retried, see IsTransient.
+	Retries int
+	// IncludeArchived syncs archived repositories too, which are left out by default.
+	IncludeArchived bool
+	// CABundle holds extra PEM certificates trusted for HTTPS remotes, e.g. for instances behind an internal PKI.
+	CABundle []byte
+	// GitProgress receives the raw git progress, nil to discard it.
+	GitProgress io.Writer
+	Reporter    Reporter
+
+	// Prepare is called while listing, before a repository is queued. It may adjust the task, or return a *Skip
+	// to leave the repository out.
+	Prepare func(ctx context.Context, task *Task) error
+	// Before is called by the worker right before cloning or pulling, and may return a *Skip as well.
+	Before func(ctx context.Context, task *Task) error
+	// After is called once the repository was cloned or pulled successfully. It may add fields to the result or
+	// change its status.
+	After func(ctx context.Context, task *Task, res *Result) error
+}
+
+// Syncer clones or updates every repository listed by a Provider with a pool of workers.
+type Syncer struct {
+	provider Provider
+	opts     Options
+
+	mu      sync.Mutex
+	locks   map[string]*sync.Mutex
+	results []Result
+}
+
+// New returns a Syncer for provider, filling in the defaults of opts.
+func New(provider Provider, opts Options) *Syncer {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	if opts.FallbackBranch == "" {
+		opts.FallbackBranch = "main"
+	}
+	if opts.Reporter == nil {
+		opts.Reporter = nopReporter{}
+	}
+	return &Syncer{provider: provider, opts: opts, locks: map[string]*sync.Mutex{}}
+}
+
+// NewTask builds the default task of a repository.
+func (s *Syncer) NewTask(repo Repository) Task {
+	branch := repo.DefaultBranch
+	if branch == "" {
+		branch = s.opts.FallbackBranch // Default branch name if not provided by the provider
+	}
+	return Task{
+		Repository: repo,
+		Dir:        filepath.Join(s.opts.BaseDir, repo.Path),
+		Branch:     branch,
+		Timeout:    s.opts.Timeout,
+		Auth:       s.provider.Auth(repo),
+		CABundle:   s.opts.CABundle,
+	}
+}
+
+// Run lists the repositories of the provider and syncs them, returning the result of every repository that
+// was not filtered out. Listing errors are returned once the repositories listed so far are synced.
+func (s *Syncer) Run(ctx context.Context) ([]Result, error) {
+	s.mu.Lock()
+	s.results = nil
+	s.mu.Unlock()
+
+	tasks := make(chan Task, s.opts.QueueSize)
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				s.Sync(ctx, task)
+			}
+		}()
+	}
+
+	start := time.Now()
+	listed := 0
+	err := s.provider.List(ctx, func(repo Repository) error {
+		listed++
+		if repo.Archived && !s.opts.IncludeArchived {
+			return nil // Skip archived repositories.
+		}
+
+		// Every accepted repository is reported as queued, so that the reporter can count on one Finished call
+		// for each, even for the ones Prepare leaves out.
+		task := s.NewTask(repo)
+		s.opts.Reporter.Queued(task)
+		if s.opts.Prepare != nil {
+			if err := s.opts.Prepare(ctx, &task); err != nil {
+				s.finish(s.failure(task, "", err, 0))
+				return nil
+			}
+		}
+
+		select {
+		case tasks <- task:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	s.opts.Reporter.Listed(listed, time.Since(start), err)
+
+	close(tasks)
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Result(nil), s.results...), err
+}
+
+// Sync clones or updates a single repository. Syncs of the same directory are serialised, so it is safe to
+// call while Run is in progress, e.g. when a webhook asks for an immediate update.
+func (s *Syncer) Sync(ctx context.Context, task Task) Result {
+	lock := s.lock(task.Dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	start := time.Now()
+	if s.opts.Before != nil {
+		if err := s.opts.Before(ctx, &task); err != nil {
+			return s.finish(s.failure(task, "", err, time.Since(start)))
+		}
+	}
+
+	action := ActionClone
+	if IsGitRepo(task.Dir) {
+		action = ActionPull
+	}
+	s.opts.Reporter.Started(task, action)
+
+	retries, err := Retry(ctx, s.opts.Retries, func() error {
+		_, err := CloneOrPull(ctx, task, s.opts.GitProgress)
+		return err
+	})
+	res := Result{Task: task, Status: StatusUpdated, Action: action, Retries: retries, Fields: map[string]any{}}
+	if err == nil && s.opts.After != nil {
+		err = s.opts.After(ctx, &task, &res)
+		res.Task = task
+	}
+	if err != nil {
+		fields, retries := res.Fields, res.Retries
+		res = s.failure(task, action, err, 0)
+		res.Fields, res.Retries = fields, retries
+	}
+	res.Duration = time.Since(start)
+	return s.finish(res)
+}
+
+// failure maps a hook or git error to a result, honouring *Skip.
+func (s *Syncer) failure(task Task, action Action, err error, elapsed time.Duration) Result {
+	res := Result{Task: task, Action: action, Status: StatusFailed, Err: err, Duration: elapsed, Fields: map[string]any{}}
+	var skip *Skip
+	if errors.As(err, &skip) {
+		res.Status = skip.Status
+		res.Reason = skip.Reason
+		res.Err = nil
+	}
+	return res
+}
+
+func (s *Syncer) finish(res Result) Result {
+	s.mu.Lock()
+	s.results = append(s.results, res)
+	s.mu.Unlock()
+
+	s.opts.Reporter.Finished(res)
+	return res
+}
+
+func (s *Syncer) lock(dir string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.locks[dir]; !ok {
+		s.locks[dir] = &sync.Mutex{}
+	}
+	return s.locks[dir]
+}
+
+// Count returns how many results have the given status.
+func Count(results []Result, status Status) int {
+	n := 0
+	for _, res := range results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
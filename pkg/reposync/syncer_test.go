@@ -0,0 +1,201 @@
+package reposync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// fakeProvider lists a fixed set of repositories, failing with err once they are all listed.
+type fakeProvider struct {
+	repos []Repository
+	err   error
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) List(ctx context.Context, fn func(Repository) error) error {
+	for _, repo := range p.repos {
+		if err := fn(repo); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+func (p *fakeProvider) Auth(Repository) transport.AuthMethod {
+	return nil
+}
+
+// newOrigin creates a repository with a single commit on main under dir, and returns its path.
+func newOrigin(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	r, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, r, path, "README.md", "# "+name)
+	return path
+}
+
+// commitFile writes a file in the worktree of r at path and commits it.
+func commitFile(t *testing.T, r *git.Repository, path, file, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(path, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := w.Commit("update "+file, &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// byPath sorts results, which workers finish in any order.
+func byPath(results []Result) []Result {
+	sort.Slice(results, func(i, j int) bool { return results[i].Task.Path < results[j].Task.Path })
+	return results
+}
+
+func TestSyncerRunClonesThenPulls(t *testing.T) {
+	origins, base := t.TempDir(), t.TempDir()
+	provider := &fakeProvider{}
+	for _, name := range []string{"api", "web"} {
+		provider.repos = append(provider.repos, Repository{
+			Path:          name,
+			FullName:      "org/" + name,
+			CloneURL:      newOrigin(t, origins, name),
+			DefaultBranch: "master",
+		})
+	}
+
+	syncer := New(provider, Options{BaseDir: base, Workers: 2})
+	results, err := syncer.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Run() returned %d results, want 2", len(results))
+	}
+	for _, res := range byPath(results) {
+		if res.Status != StatusUpdated || res.Action != ActionClone {
+			t.Errorf("%s: got %s/%s (%v), want updated/clone", res.Task.Path, res.Status, res.Action, res.Err)
+		}
+		if _, err := os.Stat(filepath.Join(base, res.Task.Path, "README.md")); err != nil {
+			t.Errorf("%s: README.md not checked out: %v", res.Task.Path, err)
+		}
+	}
+
+	// A new upstream commit is picked up by the next run.
+	r, err := git.PlainOpen(provider.repos[0].CloneURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, r, provider.repos[0].CloneURL, "CHANGELOG.md", "v2")
+
+	results, err = syncer.Run(context.Background())
+	if err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	for _, res := range byPath(results) {
+		if res.Status != StatusUpdated || res.Action != ActionPull {
+			t.Errorf("%s: got %s/%s (%v), want updated/pull", res.Task.Path, res.Status, res.Action, res.Err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base, "api", "CHANGELOG.md")); err != nil {
+		t.Errorf("new commit not pulled: %v", err)
+	}
+}
+
+func TestSyncerRunHooks(t *testing.T) {
+	origins, base := t.TempDir(), t.TempDir()
+	provider := &fakeProvider{
+		repos: []Repository{
+			{Path: "archived", CloneURL: newOrigin(t, origins, "archived"), DefaultBranch: "master", Archived: true},
+			{Path: "skipped", CloneURL: newOrigin(t, origins, "skipped"), DefaultBranch: "master"},
+			{Path: "synced", CloneURL: newOrigin(t, origins, "synced"), DefaultBranch: "master"},
+		},
+		err: errors.New("page 2 failed"),
+	}
+
+	syncer := New(provider, Options{
+		BaseDir: base,
+		Prepare: func(ctx context.Context, task *Task) error {
+			if task.Path == "skipped" {
+				return &Skip{Status: StatusSkipped, Reason: "filtered"}
+			}
+			return nil
+		},
+		After: func(ctx context.Context, task *Task, res *Result) error {
+			res.Fields["branch"] = task.Branch
+			return nil
+		},
+	})
+	results, err := syncer.Run(context.Background())
+	if err == nil || err.Error() != "page 2 failed" {
+		t.Errorf("Run() error = %v, want the listing error", err)
+	}
+
+	results = byPath(results)
+	if len(results) != 2 {
+		t.Fatalf("Run() returned %d results, want 2 as archived repositories are left out", len(results))
+	}
+	if res := results[0]; res.Status != StatusSkipped || res.Reason != "filtered" || res.Err != nil {
+		t.Errorf("skipped: got %s %q (%v), want skipped \"filtered\"", res.Status, res.Reason, res.Err)
+	}
+	if res := results[1]; res.Status != StatusUpdated || res.Fields["branch"] != "master" {
+		t.Errorf("synced: got %s with fields %v (%v), want updated with the branch field", res.Status, res.Fields, res.Err)
+	}
+	if IsGitRepo(filepath.Join(base, "skipped")) || IsGitRepo(filepath.Join(base, "archived")) {
+		t.Error("skipped or archived repositories were cloned")
+	}
+}
+
+func TestSyncerSyncFailure(t *testing.T) {
+	base := t.TempDir()
+	syncer := New(&fakeProvider{}, Options{BaseDir: base, Retries: 2})
+
+	task := syncer.NewTask(Repository{Path: "missing", CloneURL: filepath.Join(t.TempDir(), "missing")})
+	res := syncer.Sync(context.Background(), task)
+	if res.Status != StatusFailed || res.Err == nil {
+		t.Errorf("Sync() = %s (%v), want a failure", res.Status, res.Err)
+	}
+	// A missing repository is not transient, so it's not retried.
+	if res.Retries != 0 {
+		t.Errorf("Sync() retried %d times, want 0", res.Retries)
+	}
+	if task.Branch != "main" {
+		t.Errorf("NewTask() branch = %q, want the fallback branch", task.Branch)
+	}
+}
+
+func TestSyncerSyncCancelled(t *testing.T) {
+	origin := newOrigin(t, t.TempDir(), "api")
+	syncer := New(&fakeProvider{}, Options{BaseDir: t.TempDir(), Timeout: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := syncer.Sync(ctx, syncer.NewTask(Repository{Path: "api", CloneURL: origin, DefaultBranch: "master"}))
+	if res.Status != StatusFailed || !errors.Is(res.Err, context.Canceled) {
+		t.Errorf("Sync() = %s (%v), want a failure with context.Canceled", res.Status, res.Err)
+	}
+}